@@ -21,8 +21,61 @@ func testSelect(t *testing.T) {
 	}
 }
 
+func testAndWhere(t *testing.T) {
+	var clause Clause
+	clause.Set(SELECT, "User", []string{"*"})
+	clause.AndWhere("Name = ? OR Name = ?", "Tom", "Sam")
+	clause.AndWhere("Age > ?", 18)
+	sql, vars := clause.Build(SELECT, WHERE)
+	t.Log(sql, vars)
+	if sql != "SELECT * FROM User WHERE (Name = ? OR Name = ?) AND (Age > ?)" {
+		t.Fatal("failed to build SQL")
+	}
+	if !reflect.DeepEqual(vars, []interface{}{"Tom", "Sam", 18}) {
+		t.Fatal("failed to build SQLVars")
+	}
+}
+
+func testBulkUpdate(t *testing.T) {
+	var clause Clause
+	clause.Set(BULKUPDATE, "User", "Name", []string{"Age"}, []interface{}{"Tom", 18}, []interface{}{"Sam", 25})
+	clause.Set(WHERE, "Name IN (?, ?)", "Tom", "Sam")
+	sql, vars := clause.Build(BULKUPDATE, WHERE)
+	t.Log(sql, vars)
+	if sql != "UPDATE User SET Age = CASE Name WHEN ? THEN ? WHEN ? THEN ? END WHERE Name IN (?, ?)" {
+		t.Fatal("failed to build SQL")
+	}
+	if !reflect.DeepEqual(vars, []interface{}{"Tom", 18, "Sam", 25, "Tom", "Sam"}) {
+		t.Fatal("failed to build SQLVars")
+	}
+}
+
+func testExpr(t *testing.T) {
+	var clause Clause
+	clause.Set(UPDATE, "User", map[string]interface{}{"Age": Expr{SQL: "Age + ?", Vars: []interface{}{1}}})
+	clause.Set(WHERE, "Name = ? AND Age > ?", "Tom", Expr{SQL: "LENGTH(Name) * ?", Vars: []interface{}{2}})
+	clause.AndWhere("Age < ?", Expr{SQL: "100"})
+	sql, vars := clause.Build(UPDATE, WHERE)
+	t.Log(sql, vars)
+	if sql != "UPDATE User SET Age = Age + ? WHERE (Name = ? AND Age > LENGTH(Name) * ?) AND (Age < 100)" {
+		t.Fatal("failed to build SQL")
+	}
+	if !reflect.DeepEqual(vars, []interface{}{1, "Tom", 2}) {
+		t.Fatal("failed to build SQLVars")
+	}
+}
+
 func TestClause_Build(t *testing.T) {
 	t.Run("select", func(t *testing.T) {
 		testSelect(t)
 	})
+	t.Run("and where", func(t *testing.T) {
+		testAndWhere(t)
+	})
+	t.Run("expr", func(t *testing.T) {
+		testExpr(t)
+	})
+	t.Run("bulk update", func(t *testing.T) {
+		testBulkUpdate(t)
+	})
 }
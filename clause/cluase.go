@@ -1,6 +1,9 @@
 package clause
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
 type Clause struct {
 	sql     map[Type]string
@@ -19,6 +22,7 @@ const (
 	UPDATE
 	DELETE
 	COUNT
+	BULKUPDATE
 )
 
 // Set 方法根据 Type 调用对应的 generator，生成该子句对应的 SQL 子语句
@@ -32,6 +36,34 @@ func (c *Clause) Set(name Type, vars ...interface{}) {
 	c.sqlVars[name] = vars
 }
 
+// AndWhere 在已有的 WHERE 子句上以 AND 的方式追加条件，没有 WHERE 子句时等同于 Set(WHERE, ...)
+func (c *Clause) AndWhere(desc string, vars ...interface{}) {
+	old, ok := c.sql[WHERE]
+	if !ok {
+		c.Set(WHERE, append([]interface{}{desc}, vars...)...)
+		return
+	}
+	// 两边都加上括号，避免 OR 条件改变优先级
+	desc, vars = expand(desc, vars)
+	c.sql[WHERE] = fmt.Sprintf("WHERE (%s) AND (%s)", strings.TrimPrefix(old, "WHERE "), desc)
+	c.sqlVars[WHERE] = append(c.sqlVars[WHERE], vars...)
+}
+
+// Clone 返回一个独立的副本，修改副本不会影响原来的子句
+func (c *Clause) Clone() Clause {
+	clone := Clause{
+		sql:     make(map[Type]string, len(c.sql)),
+		sqlVars: make(map[Type][]interface{}, len(c.sqlVars)),
+	}
+	for name, sql := range c.sql {
+		clone.sql[name] = sql
+	}
+	for name, vars := range c.sqlVars {
+		clone.sqlVars[name] = append([]interface{}{}, vars...)
+	}
+	return clone
+}
+
 // Build 方法根据传入的 Type 的顺序，构造出最终的 SQL 语句
 func (c *Clause) Build(orders ...Type) (string, []interface{}) {
 	var sqls []string
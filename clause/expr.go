@@ -0,0 +1,44 @@
+package clause
+
+import "strings"
+
+// Expr 是原样拼接到语句中的 SQL 片段，Vars 是片段中 ? 对应的参数
+// 用于不能被当作列名校验、也不能被当作参数绑定的内容，例如 OrderBy(clause.Expr{SQL: "FIELD(Status, ?, ?)", Vars: ...})
+// 作为 Update 的值或者 Where 的参数时，Expr 会替换对应的 ?，例如 Update("Count", clause.Expr{SQL: "Count + ?", Vars: []interface{}{1}})
+type Expr struct {
+	SQL  string
+	Vars []interface{}
+}
+
+// expand 把 vars 中的 Expr 展开到 sql 中对应的 ? 位置上，并把 Expr 的参数按顺序放入 vars
+func expand(sql string, vars []interface{}) (string, []interface{}) {
+	hasExpr := false
+	for _, v := range vars {
+		if _, ok := v.(Expr); ok {
+			hasExpr = true
+			break
+		}
+	}
+	if !hasExpr {
+		return sql, vars
+	}
+
+	var b strings.Builder
+	var expanded []interface{}
+	i := 0
+	for _, r := range sql {
+		if r != '?' || i >= len(vars) {
+			b.WriteRune(r)
+			continue
+		}
+		if expr, ok := vars[i].(Expr); ok {
+			b.WriteString(expr.SQL)
+			expanded = append(expanded, expr.Vars...)
+		} else {
+			b.WriteRune(r)
+			expanded = append(expanded, vars[i])
+		}
+		i++
+	}
+	return b.String(), append(expanded, vars[i:]...)
+}
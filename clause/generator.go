@@ -20,6 +20,7 @@ func init() {
 	generators[UPDATE] = _update
 	generators[DELETE] = _delete
 	generators[COUNT] = _count
+	generators[BULKUPDATE] = _bulkUpdate
 }
 
 func genBindVars(num int) string {
@@ -65,13 +66,13 @@ func _values(values ...interface{}) (string, []interface{}) {
 	return sql.String(), vars
 }
 
-// 第一个参数表名，第二个参数字段名
+// 第一个参数表名，第二个参数字段名，之后的参数是字段中 Expr 的 vars
 func _select(values ...interface{}) (string, []interface{}) {
 	// SELECT $fields FROM $tableName
 	tableName := values[0].(string)
 	field := strings.Join(values[1].([]string), ", ")
 	sql := fmt.Sprintf("SELECT %s FROM %s", field, tableName)
-	return sql, []interface{}{}
+	return sql, values[2:]
 }
 
 // 参数为limit数
@@ -82,24 +83,30 @@ func _limit(values ...interface{}) (string, []interface{}) {
 
 // 第一个参数where条件，之后参数都是vars
 func _where(values ...interface{}) (string, []interface{}) {
-	sql := fmt.Sprintf("WHERE %s", values[0])
-	vars := values[1:]
+	desc, vars := expand(values[0].(string), values[1:])
+	sql := fmt.Sprintf("WHERE %s", desc)
 	return sql, vars
 }
 
-// 参数为orderBy 语句
+// 第一个参数为orderBy 语句，之后参数都是vars
 func _orderBy(values ...interface{}) (string, []interface{}) {
 	sql := fmt.Sprintf("ORDER BY %s", values[0])
-	return sql, []interface{}{}
+	return sql, values[1:]
 }
 
 // 第一个参数是表名(table)，第二个参数是 map 类型，表示待更新的键值对。
+// 值为 Expr 时直接拼接 SQL 片段，例如 Count = Count + ?
 func _update(values ...interface{}) (string, []interface{}) {
 	tableName := values[0].(string)
 	m := values[1].(map[string]interface{})
 	var keys []string
 	var vars []interface{}
 	for key, value := range m {
+		if expr, ok := value.(Expr); ok {
+			keys = append(keys, key+" = "+expr.SQL)
+			vars = append(vars, expr.Vars...)
+			continue
+		}
 		keys = append(keys, key+" = ?")
 		vars = append(vars, value)
 	}
@@ -116,3 +123,28 @@ func _delete(values ...interface{}) (string, []interface{}) {
 func _count(values ...interface{}) (string, []interface{}) {
 	return _select(values[0], []string{"count(*)"})
 }
+
+// 第一个参数是表名，第二个参数是主键名，第三个参数是待更新的字段名，之后的参数是每条记录的 [主键, 字段值...]
+func _bulkUpdate(values ...interface{}) (string, []interface{}) {
+	// UPDATE $tableName SET $col = CASE $pk WHEN ? THEN ? ... END, ...
+	tableName := values[0].(string)
+	pk := values[1].(string)
+	columns := values[2].([]string)
+	rows := values[3:]
+
+	var sets []string
+	var vars []interface{}
+	for i, column := range columns {
+		var sql strings.Builder
+		sql.WriteString(fmt.Sprintf("%s = CASE %s", column, pk))
+		for _, row := range rows {
+			r := row.([]interface{})
+			sql.WriteString(" WHEN ? THEN ?")
+			vars = append(vars, r[0], r[i+1])
+		}
+		sql.WriteString(" END")
+		sets = append(sets, sql.String())
+	}
+	sql := fmt.Sprintf("UPDATE %s SET %s", tableName, strings.Join(sets, ", "))
+	return sql, vars
+}
@@ -0,0 +1,136 @@
+package clause
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// BindNamed 把 sql 中的 @name 改写为占位符，并按照出现的顺序放入对应的参数，同一个名字出现多次时参数也重复多次
+// 命名参数可以是 sql.Named，也可以是一个 map[string]interface{} 或结构体（按字段名匹配），其余参数依次对应 sql 中的 ?
+// bindVar 返回第 n 个参数（从 1 开始）的占位符，通常是 Dialect.BindVar，为 nil 时使用 ?，? 也会被改写为 bindVar 返回的占位符
+// vars 中没有命名参数时 @name 原样保留，因此 MySQL 的 @var 用户变量不受影响；@@ 开头的系统变量和引号中的内容不会被改写
+func BindNamed(sql string, vars []interface{}, bindVar func(n int) string) (string, []interface{}, error) {
+	if bindVar == nil {
+		bindVar = questionMark
+	}
+	named, positional := splitNamed(vars)
+	if len(named) == 0 && bindVar(1) == "?" {
+		return sql, vars, nil
+	}
+
+	var b strings.Builder
+	var bound []interface{}
+	var quote byte
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			// 引号中的内容原样保留，\ 转义的字符不会结束引号
+			if c == '\\' && i+1 < len(sql) {
+				b.WriteByte(c)
+				i++
+				c = sql[i]
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?':
+			if len(positional) == 0 {
+				return "", nil, fmt.Errorf("missing argument for placeholder ? in %q", sql)
+			}
+			bound, positional = append(bound, positional[0]), positional[1:]
+			b.WriteString(bindVar(len(bound)))
+			continue
+		case c == '@' && i+1 < len(sql) && sql[i+1] == '@':
+			b.WriteString("@@")
+			i++
+			continue
+		case c == '@' && len(named) > 0:
+			j := i + 1
+			for j < len(sql) && isNameChar(sql[j]) {
+				j++
+			}
+			if j == i+1 {
+				break
+			}
+			name := sql[i+1 : j]
+			v, ok := lookupNamed(named, name)
+			if !ok {
+				return "", nil, fmt.Errorf("missing named argument @%s", name)
+			}
+			bound = append(bound, v)
+			b.WriteString(bindVar(len(bound)))
+			i = j - 1
+			continue
+		}
+		b.WriteByte(c)
+	}
+	if len(positional) > 0 {
+		return "", nil, fmt.Errorf("too many arguments for %q", sql)
+	}
+	return b.String(), bound, nil
+}
+
+func questionMark(int) string {
+	return "?"
+}
+
+// splitNamed 把参数分为命名参数来源和按位置绑定的参数
+func splitNamed(vars []interface{}) (named []interface{}, positional []interface{}) {
+	for _, v := range vars {
+		if isNamed(v) {
+			named = append(named, v)
+		} else {
+			positional = append(positional, v)
+		}
+	}
+	return
+}
+
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// isNamed 判断参数是否提供命名参数，time.Time、driver.Valuer 和 Expr 这类结构体是普通参数
+func isNamed(v interface{}) bool {
+	switch v.(type) {
+	case sql.NamedArg, map[string]interface{}:
+		return true
+	case time.Time, Expr:
+		return false
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	return rv.Kind() == reflect.Struct && !rv.Type().Implements(valuerType) && !reflect.PointerTo(rv.Type()).Implements(valuerType)
+}
+
+// lookupNamed 按照参数的顺序查找 name，靠前的参数优先
+func lookupNamed(named []interface{}, name string) (interface{}, bool) {
+	for _, v := range named {
+		switch arg := v.(type) {
+		case sql.NamedArg:
+			if arg.Name == name {
+				return arg.Value, true
+			}
+		case map[string]interface{}:
+			if value, ok := arg[name]; ok {
+				return value, true
+			}
+		default:
+			rv := reflect.Indirect(reflect.ValueOf(v))
+			if f, ok := rv.Type().FieldByName(name); ok && f.IsExported() {
+				return rv.FieldByIndex(f.Index).Interface(), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
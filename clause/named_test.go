@@ -0,0 +1,68 @@
+package clause
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBindNamed(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		Name string
+		SQL  string
+		Vars []interface{}
+		Want string
+		Args []interface{}
+	}{
+		{"positional", "Name = ? AND Age > ?", []interface{}{"Tom", 18}, "Name = ? AND Age > ?", []interface{}{"Tom", 18}},
+		{"user variable", "SELECT @rank := 0", nil, "SELECT @rank := 0", nil},
+		{"sql.Named", "Name = @name OR Nick = @name", []interface{}{sql.Named("name", "Tom")},
+			"Name = ? OR Nick = ?", []interface{}{"Tom", "Tom"}},
+		{"map", "Age BETWEEN @min AND @max", []interface{}{map[string]interface{}{"min": 18, "max": 30}},
+			"Age BETWEEN ? AND ?", []interface{}{18, 30}},
+		{"struct", "Name = @Name AND Age = @Age", []interface{}{struct {
+			Name string
+			Age  int
+		}{"Tom", 18}}, "Name = ? AND Age = ?", []interface{}{"Tom", 18}},
+		{"mixed", "Name = @name AND CreatedAt < ? AND Age > ?", []interface{}{sql.Named("name", "Tom"), now, 18},
+			"Name = ? AND CreatedAt < ? AND Age > ?", []interface{}{"Tom", now, 18}},
+		{"quoted", "Name = '@name' AND Note = 'it\\'s @name' AND @@autocommit = 1 AND Nick = @name", []interface{}{sql.Named("name", "Tom")},
+			"Name = '@name' AND Note = 'it\\'s @name' AND @@autocommit = 1 AND Nick = ?", []interface{}{"Tom"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			got, args, err := BindNamed(c.SQL, c.Vars, nil)
+			if err != nil || got != c.Want || !reflect.DeepEqual(args, c.Args) {
+				t.Fatalf("expect %s %v, but got %s %v %v", c.Want, c.Args, got, args, err)
+			}
+		})
+	}
+}
+
+func TestBindNamed_Errors(t *testing.T) {
+	if _, _, err := BindNamed("Name = @name AND Age = @age", []interface{}{sql.Named("name", "Tom")}, nil); err == nil {
+		t.Fatal("expect error for missing named argument")
+	}
+	if _, _, err := BindNamed("Name = @name AND Age = ?", []interface{}{sql.Named("name", "Tom")}, nil); err == nil {
+		t.Fatal("expect error for missing positional argument")
+	}
+	if _, _, err := BindNamed("Name = @name", []interface{}{sql.Named("name", "Tom"), 18}, nil); err == nil {
+		t.Fatal("expect error for too many arguments")
+	}
+}
+
+func TestBindNamed_BindVar(t *testing.T) {
+	dollar := func(n int) string { return fmt.Sprintf("$%d", n) }
+	got, args, err := BindNamed("Name = @name AND Age > ? AND Nick = @name", []interface{}{sql.Named("name", "Tom"), 18}, dollar)
+	if err != nil || got != "Name = $1 AND Age > $2 AND Nick = $3" || !reflect.DeepEqual(args, []interface{}{"Tom", 18, "Tom"}) {
+		t.Fatal("failed to bind with dialect placeholders", got, args, err)
+	}
+	got, _, err = BindNamed("SELECT @rank := ? WHERE Note = '?'", []interface{}{0}, dollar)
+	if err != nil || got != "SELECT @rank := $1 WHERE Note = '?'" {
+		t.Fatal("failed to rewrite positional placeholders", got, err)
+	}
+}
@@ -1,14 +1,78 @@
 package dialect
 
-import "reflect"
+import (
+	"database/sql"
+	"reflect"
+)
 
 var dialectsMap = map[string]Dialect{}
 
 type Dialect interface {
 	// DataTypeOf 将Go语言的类型转换为该数据库的数据类型
 	DataTypeOf(typ reflect.Value) string
+	// ColumnTypeOf 与 DataTypeOf 相同，但同时参考 size、precision 等标签中的配置
+	ColumnTypeOf(typ reflect.Value, col ColumnType) string
+	// Quote 给表名、列名等标识符加上引号，. 分隔的每一部分分别加引号
+	Quote(identifier string) string
 	// TableExistSQL 返回某个表是否存在的SQL
 	TableExistSQL(tableName string) (string, []interface{})
+	// SavepointSQL 返回创建保存点的SQL
+	SavepointSQL(name string) string
+	// RollbackToSavepointSQL 返回回滚到保存点的SQL
+	RollbackToSavepointSQL(name string) string
+	// ReleaseSavepointSQL 返回释放保存点的SQL
+	ReleaseSavepointSQL(name string) string
+	// IsRetryableError 判断错误是否可以通过重试整个事务解决，例如死锁和锁等待超时
+	IsRetryableError(err error) bool
+	// MaxPlaceholders 返回一条语句中最多可以使用的参数个数
+	MaxPlaceholders() int
+	// BindVar 返回第 n 个参数（从 1 开始）在 SQL 中的占位符，例如 MySQL 的 ? 或 PostgreSQL 的 $1
+	BindVar(n int) string
+}
+
+// ColumnType 是 go-orm 标签中影响列类型的配置，零值表示使用默认的类型映射
+type ColumnType struct {
+	// Size 是字符串和 []byte 的最大长度，或者整数的位数
+	Size int
+	// Precision 和 Scale 是小数的总位数和小数位数，time.Time 的 Precision 是秒的小数位数
+	Precision int
+	Scale     int
+	// Enum 是字符串字段允许的取值
+	Enum []string
+}
+
+// Upserter 是可选接口，支持 INSERT ... ON DUPLICATE KEY UPDATE 一类语法的数据库可以实现它
+type Upserter interface {
+	// UpsertSQL 返回追加在 INSERT 语句之后的子句，主键冲突时用插入的值更新 columns
+	UpsertSQL(columns []string) string
+}
+
+// JSONer 是可选接口，支持原生 JSON 类型的数据库实现它
+// 序列化为 JSON 的字段使用 JSONDataType 作为列类型，否则使用 TEXT
+type JSONer interface {
+	// JSONDataType 返回 JSON 列的类型
+	JSONDataType() string
+	// JSONExtractSQL 返回读取 column 中某个路径的值的表达式，路径作为参数传入
+	JSONExtractSQL(column string) string
+	// JSONContainsSQL 返回 column 包含某个 JSON 文档的条件，JSON 文档作为参数传入
+	JSONContainsSQL(column string) string
+}
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// NullableElem 判断类型对应的列是否可以为 NULL，是则返回实际保存的值的类型
+// 指针返回指向的类型，sql.NullString、sql.Null[T] 等返回值字段的类型，例如 sql.NullInt64 对应 int64
+func NullableElem(typ reflect.Type) (reflect.Type, bool) {
+	if typ.Kind() == reflect.Ptr {
+		return typ.Elem(), true
+	}
+	// sql.Null* 都是 {值, Valid bool} 两个字段的结构体，并且指针实现了 sql.Scanner
+	if typ.Kind() == reflect.Struct && typ.NumField() == 2 &&
+		typ.Field(1).Name == "Valid" && typ.Field(1).Type.Kind() == reflect.Bool &&
+		reflect.PointerTo(typ).Implements(scannerType) {
+		return typ.Field(0).Type, true
+	}
+	return nil, false
 }
 
 func RegisterDialect(name string, dialect Dialect) {
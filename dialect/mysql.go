@@ -1,9 +1,15 @@
 package dialect
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
 )
 
 type mysql struct{}
@@ -16,26 +22,146 @@ func init() {
 
 // DataTypeOf 函数用于将 Go 数据类型映射为 MySQL 数据类型
 func (s *mysql) DataTypeOf(typ reflect.Value) string {
+	return s.ColumnTypeOf(typ, ColumnType{})
+}
+
+// ColumnTypeOf 根据 Go 数据类型和标签中的 size、precision、scale、enum 配置生成 MySQL 列类型
+// 实现了 DataTyper 的类型使用自己声明的列类型，指针和 sql.Null* 类型按照实际保存的值的类型映射
+func (s *mysql) ColumnTypeOf(typ reflect.Value, col ColumnType) string {
+	if dataType, ok := dataTypeOf(typ.Type()); ok {
+		return dataType
+	}
+	if elem, ok := NullableElem(typ.Type()); ok {
+		return s.ColumnTypeOf(reflect.New(elem).Elem(), col)
+	}
 	switch typ.Kind() {
 	case reflect.Bool:
 		return "BOOLEAN"
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
-		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uintptr:
-		return "INT"
-	case reflect.Int64, reflect.Uint64:
-		return "BIGINT"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return intType(typ.Type().Bits(), typ.Kind() == reflect.Int, col)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return intType(typ.Type().Bits(), typ.Kind() == reflect.Uint || typ.Kind() == reflect.Uintptr, col) + " UNSIGNED"
 	case reflect.Float32, reflect.Float64:
+		if col.Precision > 0 {
+			return fmt.Sprintf("DECIMAL(%d,%d)", col.Precision, col.Scale)
+		}
+		if typ.Kind() == reflect.Float32 {
+			return "FLOAT"
+		}
 		return "DOUBLE"
 	case reflect.String:
-		return "VARCHAR(255)" // 对于字符串，通常指定一个长度，这里简单用 255
+		if len(col.Enum) > 0 {
+			return enumType(col.Enum)
+		}
+		return stringType(col.Size)
 	case reflect.Array, reflect.Slice:
-		return "BLOB"
+		if typ.Type() == reflect.TypeOf(json.RawMessage{}) {
+			return "JSON"
+		}
+		return blobType(col.Size)
 	case reflect.Struct:
 		if _, ok := typ.Interface().(time.Time); ok {
+			// DATETIME(fsp) 保存 fsp 位秒的小数部分，最多 6 位
+			if col.Precision > 0 {
+				return fmt.Sprintf("DATETIME(%d)", min(col.Precision, 6))
+			}
 			return "DATETIME"
 		}
+		// 实现了 driver.Valuer 的结构体按照 Value 返回的值的类型映射
+		if dataType, ok := s.valuerDataType(typ); ok {
+			return dataType
+		}
 	}
-	panic(fmt.Sprintf("invalid sql type %s (%s)", typ.Type().Name(), typ.Kind()))
+	panic(fmt.Sprintf("invalid sql type %s (%s), implement DataTyper or register the type", typ.Type().Name(), typ.Kind()))
+}
+
+// intType 根据位数选择整数类型，size 标签可以覆盖类型本身的位数
+// int 和 uint 的位数与平台有关，默认映射为 INT
+func intType(bits int, platform bool, col ColumnType) string {
+	if platform {
+		bits = 32
+	}
+	if col.Size > 0 {
+		bits = col.Size
+	}
+	switch {
+	case bits <= 8:
+		return "TINYINT"
+	case bits <= 16:
+		return "SMALLINT"
+	case bits <= 24:
+		return "MEDIUMINT"
+	case bits <= 32:
+		return "INT"
+	}
+	return "BIGINT"
+}
+
+// stringType 根据最大长度选择字符串类型，没有指定长度时使用 VARCHAR(255)
+// VARCHAR 的长度受行大小 65535 字节的限制，utf8mb4 下最多 16383 个字符，更长的字符串使用 TEXT 系列
+func stringType(size int) string {
+	switch {
+	case size <= 0:
+		return "VARCHAR(255)"
+	case size <= 16383:
+		return fmt.Sprintf("VARCHAR(%d)", size)
+	case size <= 65535:
+		return "TEXT"
+	case size <= 16777215:
+		return "MEDIUMTEXT"
+	}
+	return "LONGTEXT"
+}
+
+func blobType(size int) string {
+	switch {
+	case size <= 65535:
+		return "BLOB"
+	case size <= 16777215:
+		return "MEDIUMBLOB"
+	}
+	return "LONGBLOB"
+}
+
+func enumType(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, "'"+strings.ReplaceAll(v, "'", "''")+"'")
+	}
+	return "ENUM(" + strings.Join(quoted, ",") + ")"
+}
+
+// valuerDataType 调用零值的 Value 方法，根据 driver.Value 的类型推断列类型
+// 零值返回 nil 或者 Value 方法 panic 时无法推断，需要实现 DataTyper 或者注册类型
+func (s *mysql) valuerDataType(typ reflect.Value) (dataType string, ok bool) {
+	ptr := reflect.New(typ.Type())
+	ptr.Elem().Set(typ)
+	valuer, ok := ptr.Interface().(driver.Valuer)
+	if !ok {
+		return "", false
+	}
+	defer func() {
+		if recover() != nil {
+			dataType, ok = "", false
+		}
+	}()
+	value, err := valuer.Value()
+	if err != nil || value == nil {
+		return "", false
+	}
+	if _, ok := value.(time.Time); ok {
+		return "DATETIME", true
+	}
+	return s.DataTypeOf(reflect.ValueOf(value)), true
+}
+
+// Quote 使用反引号包裹标识符，标识符中的反引号写成两个
+func (s *mysql) Quote(identifier string) string {
+	parts := strings.Split(identifier, ".")
+	for i, part := range parts {
+		parts[i] = "`" + strings.ReplaceAll(part, "`", "``") + "`"
+	}
+	return strings.Join(parts, ".")
 }
 
 // TableExistSQL 函数用于生成检查 MySQL 中表是否存在的 SQL 语句和参数
@@ -43,3 +169,61 @@ func (s *mysql) TableExistSQL(tableName string) (string, []interface{}) {
 	args := []interface{}{tableName}
 	return "SELECT TABLE_NAME FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", args
 }
+
+func (s *mysql) SavepointSQL(name string) string {
+	return "SAVEPOINT " + name
+}
+
+func (s *mysql) RollbackToSavepointSQL(name string) string {
+	return "ROLLBACK TO SAVEPOINT " + name
+}
+
+func (s *mysql) ReleaseSavepointSQL(name string) string {
+	return "RELEASE SAVEPOINT " + name
+}
+
+// MySQL 中可以通过重试事务解决的错误码
+const (
+	errLockWaitTimeout = 1205
+	errLockDeadlock    = 1213
+)
+
+// IsRetryableError 死锁（1213）和锁等待超时（1205）时可以重试事务
+func (s *mysql) IsRetryableError(err error) bool {
+	var mysqlErr *mysqldriver.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == errLockDeadlock || mysqlErr.Number == errLockWaitTimeout
+	}
+	return false
+}
+
+// MaxPlaceholders MySQL 的预处理语句最多支持 65535 个参数
+func (s *mysql) MaxPlaceholders() int {
+	return 65535
+}
+
+// BindVar MySQL 的占位符与参数的位置无关，都是 ?
+func (s *mysql) BindVar(n int) string {
+	return "?"
+}
+
+// UpsertSQL 生成 ON DUPLICATE KEY UPDATE col = VALUES(col), ...
+func (s *mysql) UpsertSQL(columns []string) string {
+	sets := make([]string, 0, len(columns))
+	for _, column := range columns {
+		sets = append(sets, fmt.Sprintf("%s = VALUES(%s)", column, column))
+	}
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+}
+
+func (s *mysql) JSONDataType() string {
+	return "JSON"
+}
+
+func (s *mysql) JSONExtractSQL(column string) string {
+	return fmt.Sprintf("JSON_EXTRACT(%s, ?)", column)
+}
+
+func (s *mysql) JSONContainsSQL(column string) string {
+	return fmt.Sprintf("JSON_CONTAINS(%s, ?)", column)
+}
@@ -1,8 +1,16 @@
 package dialect
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"reflect"
 	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
 )
 
 func TestDataTypeOf(t *testing.T) {
@@ -15,6 +23,10 @@ func TestDataTypeOf(t *testing.T) {
 		{123, "INT"},
 		{1.2, "DOUBLE"},
 		{[]int{1, 2, 3}, "BLOB"},
+		{new(string), "VARCHAR(255)"},
+		{sql.NullInt64{}, "BIGINT"},
+		{sql.NullTime{}, "DATETIME"},
+		{sql.Null[float64]{}, "DOUBLE"},
 	}
 
 	for _, c := range cases {
@@ -23,3 +35,154 @@ func TestDataTypeOf(t *testing.T) {
 		}
 	}
 }
+
+type status string
+
+func TestColumnTypeOf(t *testing.T) {
+	dial := &mysql{}
+	cases := []struct {
+		Name   string
+		Value  interface{}
+		Column ColumnType
+		Type   string
+	}{
+		{"bool", true, ColumnType{}, "BOOLEAN"},
+		{"int", 1, ColumnType{}, "INT"},
+		{"int8", int8(1), ColumnType{}, "TINYINT"},
+		{"int16", int16(1), ColumnType{}, "SMALLINT"},
+		{"int32", int32(1), ColumnType{}, "INT"},
+		{"int64", int64(1), ColumnType{}, "BIGINT"},
+		{"uint", uint(1), ColumnType{}, "INT UNSIGNED"},
+		{"uint8", uint8(1), ColumnType{}, "TINYINT UNSIGNED"},
+		{"uint16", uint16(1), ColumnType{}, "SMALLINT UNSIGNED"},
+		{"uint32", uint32(1), ColumnType{}, "INT UNSIGNED"},
+		{"uint64", uint64(1), ColumnType{}, "BIGINT UNSIGNED"},
+		{"int size 8", 1, ColumnType{Size: 8}, "TINYINT"},
+		{"int size 24", 1, ColumnType{Size: 24}, "MEDIUMINT"},
+		{"int size 64", 1, ColumnType{Size: 64}, "BIGINT"},
+		{"uint size 16", uint(1), ColumnType{Size: 16}, "SMALLINT UNSIGNED"},
+		{"pointer uint64", new(uint64), ColumnType{}, "BIGINT UNSIGNED"},
+		{"float32", float32(1), ColumnType{}, "FLOAT"},
+		{"float64", float64(1), ColumnType{}, "DOUBLE"},
+		{"decimal", float64(1), ColumnType{Precision: 10, Scale: 2}, "DECIMAL(10,2)"},
+		{"decimal without scale", float32(1), ColumnType{Precision: 8}, "DECIMAL(8,0)"},
+		{"null decimal", sql.NullFloat64{}, ColumnType{Precision: 12, Scale: 4}, "DECIMAL(12,4)"},
+		{"string", "", ColumnType{}, "VARCHAR(255)"},
+		{"string size 64", "", ColumnType{Size: 64}, "VARCHAR(64)"},
+		{"string size 16383", "", ColumnType{Size: 16383}, "VARCHAR(16383)"},
+		{"string size 16384", "", ColumnType{Size: 16384}, "TEXT"},
+		{"string size 65535", "", ColumnType{Size: 65535}, "TEXT"},
+		{"string size 65536", "", ColumnType{Size: 65536}, "MEDIUMTEXT"},
+		{"string size 16777216", "", ColumnType{Size: 16777216}, "LONGTEXT"},
+		{"null string size", sql.NullString{}, ColumnType{Size: 32}, "VARCHAR(32)"},
+		{"enum", status(""), ColumnType{Enum: []string{"draft", "published"}}, "ENUM('draft','published')"},
+		{"enum quote", "", ColumnType{Enum: []string{"it's"}}, "ENUM('it''s')"},
+		{"bytes", []byte{}, ColumnType{}, "BLOB"},
+		{"bytes size 65535", []byte{}, ColumnType{Size: 65535}, "BLOB"},
+		{"bytes size 65536", []byte{}, ColumnType{Size: 65536}, "MEDIUMBLOB"},
+		{"bytes size 16777216", []byte{}, ColumnType{Size: 16777216}, "LONGBLOB"},
+		{"array", [4]int{}, ColumnType{}, "BLOB"},
+		{"json", json.RawMessage{}, ColumnType{}, "JSON"},
+		{"time", time.Time{}, ColumnType{}, "DATETIME"},
+		{"time fsp 3", time.Time{}, ColumnType{Precision: 3}, "DATETIME(3)"},
+		{"time fsp 9", time.Time{}, ColumnType{Precision: 9}, "DATETIME(6)"},
+		{"pointer time fsp", new(time.Time), ColumnType{Precision: 6}, "DATETIME(6)"},
+		{"null time fsp", sql.NullTime{}, ColumnType{Precision: 3}, "DATETIME(3)"},
+		{"data typer", uuid{}, ColumnType{Size: 64}, "CHAR(36)"},
+		{"valuer", money{}, ColumnType{}, "BIGINT"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			if typ := dial.ColumnTypeOf(reflect.ValueOf(c.Value), c.Column); typ != c.Type {
+				t.Fatalf("expect %s, but got %s", c.Type, typ)
+			}
+		})
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	dial := &mysql{}
+	cases := []struct {
+		Err       error
+		Retryable bool
+	}{
+		{&mysqldriver.MySQLError{Number: 1213}, true},
+		{fmt.Errorf("insert: %w", &mysqldriver.MySQLError{Number: 1205}), true},
+		{&mysqldriver.MySQLError{Number: 1062}, false},
+		{errors.New("Error"), false},
+	}
+
+	for _, c := range cases {
+		if retryable := dial.IsRetryableError(c.Err); retryable != c.Retryable {
+			t.Fatalf("expect %v for %v, but got %v", c.Retryable, c.Err, retryable)
+		}
+	}
+}
+
+type money struct {
+	Cents int64
+}
+
+func (m money) Value() (driver.Value, error) {
+	return m.Cents, nil
+}
+
+type email string
+
+type uuid [16]byte
+
+func (uuid) OrmDataType() string {
+	return "CHAR(36)"
+}
+
+type point struct {
+	X, Y float64
+}
+
+func TestDataTypeOf_CustomTypes(t *testing.T) {
+	dial := &mysql{}
+	types := &Types{}
+	types.Register(point{}, "POINT")
+	withTypes := WithTypes(dial, types)
+	cases := []struct {
+		Value interface{}
+		Type  string
+	}{
+		{money{}, "BIGINT"},
+		{email(""), "VARCHAR(255)"},
+		{uuid{}, "CHAR(36)"},
+		{&uuid{}, "CHAR(36)"},
+		{point{}, "POINT"},
+		{&point{}, "POINT"},
+	}
+
+	for _, c := range cases {
+		if typ := withTypes.DataTypeOf(reflect.ValueOf(c.Value)); typ != c.Type {
+			t.Fatalf("expect %s, but got %s", c.Type, typ)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	dial := &mysql{}
+	cases := map[string]string{
+		"User":        "`User`",
+		"Order":       "`Order`",
+		"User.Name":   "`User`.`Name`",
+		"we`ird":      "`we``ird`",
+		"a` OR 1=1 #": "`a`` OR 1=1 #`",
+	}
+	for identifier, quoted := range cases {
+		if got := dial.Quote(identifier); got != quoted {
+			t.Fatalf("expect %s, but got %s", quoted, got)
+		}
+	}
+}
+
+func TestBindVar(t *testing.T) {
+	dial := &mysql{}
+	if dial.BindVar(1) != "?" || dial.BindVar(2) != "?" {
+		t.Fatal("expect ? for every argument")
+	}
+}
@@ -0,0 +1,73 @@
+package dialect
+
+import (
+	"reflect"
+	"sync"
+)
+
+// DataTyper 由自定义类型实现，声明自己在数据库中的列类型，优先于默认的类型映射
+// 例如 func (Money) OrmDataType() string { return "DECIMAL(20,2)" }
+type DataTyper interface {
+	OrmDataType() string
+}
+
+// dataTypeOf 返回类型通过 OrmDataType 方法声明的列类型，值接收者和指针接收者都可以
+func dataTypeOf(typ reflect.Type) (string, bool) {
+	if t, ok := reflect.New(typ).Interface().(DataTyper); ok {
+		return t.OrmDataType(), true
+	}
+	return "", false
+}
+
+// Types 记录自定义类型对应的列类型，用于无法为类型添加 OrmDataType 方法的场景，例如第三方库中的类型
+type Types struct {
+	m sync.Map // reflect.Type -> string
+}
+
+// Register 注册 value 的类型对应的列类型，value 传入该类型的零值即可
+func (t *Types) Register(value interface{}, dataType string) {
+	t.m.Store(reflect.Indirect(reflect.ValueOf(value)).Type(), dataType)
+}
+
+// Lookup 返回注册的列类型，指针和 sql.Null[T] 按照实际保存的值的类型查找
+func (t *Types) Lookup(typ reflect.Type) (string, bool) {
+	if dataType, ok := t.m.Load(typ); ok {
+		return dataType.(string), true
+	}
+	if elem, ok := NullableElem(typ); ok {
+		return t.Lookup(elem)
+	}
+	return "", false
+}
+
+// WithTypes 返回优先使用 types 中注册的列类型的 Dialect，其他方法交给 d 处理
+func WithTypes(d Dialect, types *Types) Dialect {
+	return &typesDialect{Dialect: d, types: types}
+}
+
+// Unwrap 返回被 WithTypes 包装之前的 Dialect，用于检查 Upserter、JSONer 等可选接口
+func Unwrap(d Dialect) Dialect {
+	if t, ok := d.(*typesDialect); ok {
+		return t.Dialect
+	}
+	return d
+}
+
+type typesDialect struct {
+	Dialect
+	types *Types
+}
+
+func (d *typesDialect) DataTypeOf(typ reflect.Value) string {
+	if dataType, ok := d.types.Lookup(typ.Type()); ok {
+		return dataType
+	}
+	return d.Dialect.DataTypeOf(typ)
+}
+
+func (d *typesDialect) ColumnTypeOf(typ reflect.Value, col ColumnType) string {
+	if dataType, ok := d.types.Lookup(typ.Type()); ok {
+		return dataType
+	}
+	return d.Dialect.ColumnTypeOf(typ, col)
+}
@@ -5,13 +5,24 @@ import (
 	"fmt"
 	"go-orm/dialect"
 	"go-orm/log"
+	"go-orm/schema"
 	"go-orm/session"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Engine struct {
 	db      *sql.DB
 	dialect dialect.Dialect
+	// nowFunc 是自动时间戳使用的时钟，为 nil 时使用 time.Now
+	nowFunc func() time.Time
+	// schemas 缓存模型的解析结果，由所有 Session 共享
+	schemas sync.Map
+	// types 是自定义类型的列类型注册表
+	types dialect.Types
+	// keys 是加密字段使用的密钥
+	keys schema.KeyProvider
 }
 
 type TxFunc func(*session.Session) (interface{}, error)
@@ -41,23 +52,15 @@ func NewEngine(driver, source string) (e *Engine, err error) {
 
 // Transaction 将所有的操作放到一个回调函数中，作为入参传递给 engine.Transaction()
 // 发生任何错误，自动回滚，如果没有错误发生，则提交
+// engine.Transaction 每次都使用新的 Session 开启一个独立的事务，即使在另一个 TxFunc 中调用也不会加入外层事务，
+// 两个事务分别提交和回滚，还可能因为互相等待锁而阻塞。需要嵌套事务时，在回调函数中调用 s.Transaction()，
+// 内层事务使用保存点，失败时只回滚到保存点
+//
+//	engine.Transaction(func(s *session.Session) (interface{}, error) {
+//		return s.Transaction(func(s *session.Session) (interface{}, error) { ... })
+//	})
 func (engine *Engine) Transaction(f TxFunc) (result interface{}, err error) {
-	s := engine.NewSession()
-	if err := s.Begin(); err != nil {
-		return nil, err
-	}
-	defer func() {
-		if p := recover(); p != nil {
-			_ = s.Rollback()
-			panic(p) // re-throw panic after Rollback
-		} else if err != nil {
-			_ = s.Rollback() // err is non-nil; don't change it
-		} else {
-			err = s.Commit() // err is nil; if Commit returns error update err
-		}
-	}()
-
-	return f(s)
+	return engine.NewSession().Transaction(f)
 }
 
 func (engine *Engine) Close() {
@@ -68,8 +71,31 @@ func (engine *Engine) Close() {
 	log.Info("Close database success")
 }
 
+// SetNowFunc 设置 CreatedAt/UpdatedAt 等自动时间戳使用的时钟，测试中可以固定时间
+func (engine *Engine) SetNowFunc(nowFunc func() time.Time) {
+	engine.nowFunc = nowFunc
+}
+
+// RegisterType 注册自定义类型在数据库中的列类型，例如 engine.RegisterType(uuid.UUID{}, "CHAR(36)")
+// 已经解析过的模型会被重新解析
+func (engine *Engine) RegisterType(value interface{}, dataType string) {
+	engine.types.Register(value, dataType)
+	engine.schemas.Clear()
+}
+
+// SetKeyProvider 设置 `go-orm:"encrypt"` 字段使用的密钥，已经解析过的模型会被重新解析
+func (engine *Engine) SetKeyProvider(keys schema.KeyProvider) {
+	engine.keys = keys
+	engine.schemas.Clear()
+}
+
 func (engine *Engine) NewSession() *session.Session {
-	return session.NewSession(engine.db, engine.dialect)
+	return session.NewSession(engine.db, engine.dialect,
+		session.WithNowFunc(engine.nowFunc),
+		session.WithSchemaCache(&engine.schemas),
+		session.WithTypes(&engine.types),
+		session.WithKeyProvider(engine.keys),
+	)
 }
 
 // 得到a中有的，但是b中没有的字段，a总是较少字段的那一个
@@ -97,10 +123,15 @@ func (engine *Engine) Migrate(value interface{}) error {
 			return nil, s.CreateTable()
 		}
 
+		// 补充新增的多对多连接表
+		if err = s.CreateJoinTables(); err != nil {
+			return
+		}
 		// table是新表的结构，即期望的表结构
 		table := s.RefTable()
+		quote := engine.dialect.Quote
 		// 取出第一条记录
-		rows, _ := s.Raw(fmt.Sprintf("SELECT * FROM %s LIMIT 1", table.Name)).QueryRows()
+		rows, _ := s.Raw(fmt.Sprintf("SELECT * FROM %s LIMIT 1", quote(table.Name))).QueryRows()
 		// 获取该记录的所有字段，即当前旧表的结构
 		columns, _ := rows.Columns()
 		// old A，B
@@ -114,7 +145,7 @@ func (engine *Engine) Migrate(value interface{}) error {
 		// 在原表的基础上添加新增的字段，此时包括所有旧字段 + 新表增加的字段
 		for _, col := range addCols {
 			f := table.GetField(col)
-			sql := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table.Name, f.Name, f.Type)
+			sql := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", quote(table.Name), quote(f.Name), f.Type)
 			_, err = s.Raw(sql).Exec()
 			if err != nil {
 				return
@@ -127,14 +158,18 @@ func (engine *Engine) Migrate(value interface{}) error {
 			return
 		}
 
-		tmp := "tmp_" + table.Name
+		tmp := quote("tmp_" + table.Name)
 		// 取出新表的所有字段，即所有期望的字段，A，C
-		fieldStr := strings.Join(table.FieldNames, ",")
+		var fields []string
+		for _, name := range table.FieldNames {
+			fields = append(fields, quote(name))
+		}
+		fieldStr := strings.Join(fields, ",")
 		// 创建一个tmp表，从A，B，C 中只选择A，C字段
-		s.Raw(fmt.Sprintf("CREATE TABLE %s AS SELECT %s from %s", tmp, fieldStr, table.Name))
+		s.Raw(fmt.Sprintf("CREATE TABLE %s AS SELECT %s from %s", tmp, fieldStr, quote(table.Name)))
 		// 删除旧表，并把新表改名成旧表
-		s.Raw(fmt.Sprintf("DROP TABLE %s", table.Name))
-		s.Raw(fmt.Sprintf("ALTER TABLE %s RENAME to %s", tmp, table.Name))
+		s.Raw(fmt.Sprintf("DROP TABLE %s", quote(table.Name)))
+		s.Raw(fmt.Sprintf("ALTER TABLE %s RENAME to %s", tmp, quote(table.Name)))
 		_, err = s.Exec()
 		return
 	})
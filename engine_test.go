@@ -45,6 +45,12 @@ func TestEngine_Transaction(t *testing.T) {
 	t.Run("commit", func(t *testing.T) {
 		transactionCommit(t)
 	})
+	t.Run("nested", func(t *testing.T) {
+		transactionNested(t)
+	})
+	t.Run("callbacks", func(t *testing.T) {
+		transactionCallbacks(t)
+	})
 }
 
 // TODO mysql DDL will  automatic commit
@@ -82,6 +88,72 @@ func transactionCommit(t *testing.T) {
 	}
 }
 
+func transactionNested(t *testing.T) {
+	engine := OpenDB(t)
+	defer engine.Close()
+
+	s := engine.NewSession()
+	_ = s.Model(&User{}).DropTable()
+	_ = s.Model(&User{}).CreateTable()
+
+	_, err := engine.Transaction(func(s *session.Session) (result interface{}, err error) {
+		_, err = s.Insert(&User{"Tom", 18})
+		if err != nil {
+			return
+		}
+		// 内层事务失败只回滚到保存点
+		_, innerErr := s.Transaction(func(s *session.Session) (interface{}, error) {
+			_, _ = s.Insert(&User{"Sam", 25})
+			return nil, errors.New("Error")
+		})
+		if innerErr == nil {
+			t.Fatal("expect inner transaction error")
+		}
+		return
+	})
+	count, _ := s.Model(&User{}).Count()
+	if err != nil || count != 1 {
+		t.Fatal("failed to rollback to savepoint, got count", count)
+	}
+}
+
+func transactionCallbacks(t *testing.T) {
+	engine := OpenDB(t)
+	defer engine.Close()
+
+	var events []string
+	_, err := engine.Transaction(func(s *session.Session) (interface{}, error) {
+		s.AfterCommit(func() { events = append(events, "outer commit") })
+		_, _ = s.Transaction(func(s *session.Session) (interface{}, error) {
+			s.AfterCommit(func() { events = append(events, "released commit") })
+			return nil, nil
+		})
+		_, _ = s.Transaction(func(s *session.Session) (interface{}, error) {
+			s.AfterCommit(func() { events = append(events, "discarded commit") })
+			s.AfterRollback(func() { events = append(events, "savepoint rollback") })
+			return nil, errors.New("Error")
+		})
+		if len(events) != 1 || events[0] != "savepoint rollback" {
+			t.Fatal("expect savepoint rollback callback only, but got", events)
+		}
+		return nil, nil
+	})
+	expect := []string{"savepoint rollback", "outer commit", "released commit"}
+	if err != nil || !reflect.DeepEqual(events, expect) {
+		t.Fatal("failed to run after commit callbacks, got", events)
+	}
+
+	events = nil
+	_, _ = engine.Transaction(func(s *session.Session) (interface{}, error) {
+		s.AfterCommit(func() { events = append(events, "commit") })
+		s.AfterRollback(func() { events = append(events, "rollback") })
+		return nil, errors.New("Error")
+	})
+	if !reflect.DeepEqual(events, []string{"rollback"}) {
+		t.Fatal("failed to run after rollback callbacks, got", events)
+	}
+}
+
 func TestEngine_Migrate(t *testing.T) {
 	engine := OpenDB(t)
 	defer engine.Close()
@@ -0,0 +1,11 @@
+package engine
+
+import "go-orm/clause"
+
+// Expr 创建一个原样拼接到 SQL 中的表达式，vars 是表达式中 ? 对应的参数
+// 可以作为 Update 的值、Where 的条件或参数、Select 的列以及 OrderBy 的排序
+//
+//	s.Model(&Article{}).Where("ID = ?", 1).Update("Views", engine.Expr("Views + ?", 1))
+func Expr(sql string, vars ...interface{}) clause.Expr {
+	return clause.Expr{SQL: sql, Vars: vars}
+}
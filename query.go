@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-orm/session"
+	"iter"
+	"reflect"
+)
+
+// TypedQuery 是以结构体类型 T 为模型的类型安全查询，底层仍然通过 Session 执行
+// 每个方法都返回新的 TypedQuery，同一个查询可以被复用
+//
+//	users, err := engine.Query[User](e).Where("Age > ?", 18).Find(ctx)
+type TypedQuery[T any] struct {
+	engine  *Engine
+	err     error
+	wheres  []condition
+	orderBy string
+	limit   int
+}
+
+type condition struct {
+	desc string
+	args []interface{}
+}
+
+// Query 创建模型为 T 的查询，T 必须是结构体类型
+// T 的结构只在第一次使用时解析，之后从 Engine 的缓存中读取
+func Query[T any](engine *Engine) *TypedQuery[T] {
+	q := &TypedQuery[T]{engine: engine}
+	if typ := reflect.TypeOf((*T)(nil)).Elem(); typ.Kind() != reflect.Struct {
+		q.err = fmt.Errorf("query model must be a struct, got %s", typ)
+	}
+	return q
+}
+
+func (q *TypedQuery[T]) Where(desc string, args ...interface{}) *TypedQuery[T] {
+	c := *q
+	c.wheres = append(append([]condition{}, q.wheres...), condition{desc: desc, args: args})
+	return &c
+}
+
+func (q *TypedQuery[T]) OrderBy(desc string) *TypedQuery[T] {
+	c := *q
+	c.orderBy = desc
+	return &c
+}
+
+func (q *TypedQuery[T]) Limit(num int) *TypedQuery[T] {
+	c := *q
+	c.limit = num
+	return &c
+}
+
+// Find 返回所有满足条件的记录
+func (q *TypedQuery[T]) Find(ctx context.Context) ([]T, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	var dest []T
+	err := q.session(ctx).Find(&dest)
+	return dest, err
+}
+
+// First 返回第一条满足条件的记录，没有记录时返回 session.ErrRecordNotFound
+func (q *TypedQuery[T]) First(ctx context.Context) (T, error) {
+	var dest T
+	if q.err != nil {
+		return dest, q.err
+	}
+	err := q.session(ctx).First(&dest)
+	return dest, err
+}
+
+// Get 根据主键查询一条记录
+func (q *TypedQuery[T]) Get(ctx context.Context, id interface{}) (T, error) {
+	var dest T
+	if q.err != nil {
+		return dest, q.err
+	}
+	s := q.session(ctx)
+	pk := s.RefTable().PrimaryField
+	if pk == nil {
+		return dest, errors.New("primary key not found")
+	}
+	err := s.Where(q.engine.dialect.Quote(pk.Name)+" = ?", id).First(&dest)
+	return dest, err
+}
+
+// Iter 以迭代器的方式逐行读取满足条件的记录，适合导出大量数据
+func (q *TypedQuery[T]) Iter(ctx context.Context) iter.Seq2[*T, error] {
+	if q.err != nil {
+		return func(yield func(*T, error) bool) {
+			yield(nil, q.err)
+		}
+	}
+	return session.Iter[T](q.session(ctx))
+}
+
+// Count 返回满足条件的记录数
+func (q *TypedQuery[T]) Count(ctx context.Context) (int64, error) {
+	if q.err != nil {
+		return 0, q.err
+	}
+	return q.session(ctx).Count()
+}
+
+// Insert 插入一条或多条记录
+func (q *TypedQuery[T]) Insert(ctx context.Context, values ...*T) (int64, error) {
+	if q.err != nil || len(values) == 0 {
+		return 0, q.err
+	}
+	records := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		records = append(records, value)
+	}
+	return q.engine.NewSession().WithContext(ctx).Model(new(T)).Insert(records...)
+}
+
+// session 创建一个以 T 为模型、带有当前查询条件的 Session
+func (q *TypedQuery[T]) session(ctx context.Context) *session.Session {
+	s := q.engine.NewSession().WithContext(ctx).Model(new(T))
+	for _, w := range q.wheres {
+		s.Where(w.desc, w.args...)
+	}
+	if q.orderBy != "" {
+		s.OrderBy(q.orderBy)
+	}
+	if q.limit > 0 {
+		s.Limit(q.limit)
+	}
+	return s
+}
@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"go-orm/session"
+	"testing"
+)
+
+func TestQuery_InvalidModel(t *testing.T) {
+	if _, err := Query[int](nil).Find(context.Background()); err == nil {
+		t.Fatal("expect error for non-struct model")
+	}
+}
+
+func TestQuery(t *testing.T) {
+	engine := OpenDB(t)
+	defer engine.Close()
+	ctx := context.Background()
+
+	s := engine.NewSession().Model(&User{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	if affected, err := Query[User](engine).Insert(ctx, &User{"Tom", 18}, &User{"Sam", 25}); err != nil || affected != 2 {
+		t.Fatal("failed to insert", err)
+	}
+
+	adults := Query[User](engine).Where("Age > ?", 20)
+	users, err := adults.Find(ctx)
+	if err != nil || len(users) != 1 || users[0].Name != "Sam" {
+		t.Fatal("failed to find, got", users)
+	}
+	// 查询可以复用，条件不会因为执行而丢失
+	if count, _ := adults.Count(ctx); count != 1 {
+		t.Fatal("expect 1, but got", count)
+	}
+
+	u, err := Query[User](engine).Get(ctx, "Tom")
+	if err != nil || u.Age != 18 {
+		t.Fatal("failed to get by primary key, got", u)
+	}
+	if _, err := Query[User](engine).Get(ctx, "Jack"); !errors.Is(err, session.ErrRecordNotFound) {
+		t.Fatal("expect ErrRecordNotFound, but got", err)
+	}
+}
@@ -0,0 +1,249 @@
+package schema
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// KeyProvider 提供加密字段使用的 AES 密钥，密钥长度为 16/24/32 字节，对应 AES-128/192/256
+// 密文中记录了加密时使用的密钥 ID，轮换密钥时把新密钥设为当前密钥，旧密钥保留到数据重新加密之后
+type KeyProvider interface {
+	// CurrentKeyID 返回加密新数据使用的密钥 ID
+	CurrentKeyID() string
+	// Key 返回 ID 对应的密钥
+	Key(id string) ([]byte, error)
+	// KeyIDs 返回所有可以用于解密的密钥 ID
+	KeyIDs() []string
+}
+
+// Keyring 是保存在内存中的 KeyProvider
+type Keyring struct {
+	Current string
+	Keys    map[string][]byte
+}
+
+func (k *Keyring) CurrentKeyID() string {
+	return k.Current
+}
+
+func (k *Keyring) Key(id string) ([]byte, error) {
+	key, ok := k.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("encryption key %s not found", id)
+	}
+	return key, nil
+}
+
+func (k *Keyring) KeyIDs() []string {
+	ids := make([]string, 0, len(k.Keys))
+	for id := range k.Keys {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Encryptor 使用 AES-GCM 加密 `go-orm:"encrypt"` 标签声明的字段，密文格式为 密钥ID:base64(nonce+密文)
+// 默认每次加密使用随机 nonce，`go-orm:"encrypt:deterministic"` 时 nonce 由明文的 HMAC 生成，
+// 同一个密钥下相同的明文得到相同的密文，可以用于等值查询
+type Encryptor struct {
+	keys          KeyProvider
+	deterministic bool
+	// aad 是字段名，密文不能被复制到其他字段上解密
+	aad []byte
+}
+
+// Deterministic 返回相同明文是否总是得到相同的密文
+func (e *Encryptor) Deterministic() bool {
+	return e.deterministic
+}
+
+// Encrypt 使用 keyID 对应的密钥加密 plaintext
+func (e *Encryptor) Encrypt(keyID string, plaintext []byte) (string, error) {
+	gcm, key, err := e.cipher(keyID)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if e.deterministic {
+		// 不直接使用加密密钥计算 HMAC，而是为每个字段派生出单独的子密钥，
+		// 不同字段的 字段名+明文 拼接后相同时也不会得到相同的 nonce
+		mac := hmac.New(sha256.New, subKey(key, "nonce:"+string(e.aad)))
+		mac.Write(plaintext)
+		copy(nonce, mac.Sum(nil))
+	} else if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, plaintext, e.aad)
+	return keyID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 使用密文中记录的密钥解密
+func (e *Encryptor) Decrypt(ciphertext string) ([]byte, error) {
+	i := strings.LastIndexByte(ciphertext, ':')
+	if i < 0 {
+		return nil, errors.New("invalid ciphertext: key id not found")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext[i+1:])
+	if err != nil {
+		return nil, err
+	}
+	gcm, _, err := e.cipher(ciphertext[:i])
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("invalid ciphertext: too short")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], e.aad)
+}
+
+func (e *Encryptor) cipher(keyID string) (cipher.AEAD, []byte, error) {
+	if e.keys == nil {
+		return nil, nil, errors.New("encryption key provider is not set")
+	}
+	key, err := e.keys.Key(keyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	return gcm, key, err
+}
+
+func subKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// Ciphertexts 返回 value 在每个密钥下的密文，用于确定性加密字段的等值查询，
+// 密钥轮换后尚未重新加密的记录也能被查到
+func (f *Field) Ciphertexts(value interface{}) ([]interface{}, error) {
+	if f.Encryptor == nil || !f.Encryptor.Deterministic() {
+		return nil, fmt.Errorf("field %s is not deterministically encrypted", f.Name)
+	}
+	if f.Encryptor.keys == nil || len(f.Encryptor.keys.KeyIDs()) == 0 {
+		return nil, errors.New("encryption key provider is not set")
+	}
+	plaintext, ok, err := f.plaintext(value)
+	if err != nil || !ok {
+		return nil, fmt.Errorf("can not encrypt %v for %s", value, f.Name)
+	}
+	var ciphertexts []interface{}
+	for _, id := range f.Encryptor.keys.KeyIDs() {
+		ciphertext, err := f.Encryptor.Encrypt(id, plaintext)
+		if err != nil {
+			return nil, err
+		}
+		ciphertexts = append(ciphertexts, ciphertext)
+	}
+	return ciphertexts, nil
+}
+
+// canEncrypt 判断字段类型是否可以加密，声明了 serializer 的字段加密序列化之后的数据
+func canEncrypt(typ reflect.Type, serializer Serializer) bool {
+	if serializer != nil {
+		return true
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.String || typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8
+}
+
+// plaintext 把字段的值转换为加密前的明文，nil 指针返回 false，写入 NULL
+func (f *Field) plaintext(value interface{}) ([]byte, bool, error) {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, false, nil
+	}
+	if f.Serializer != nil {
+		data, err := f.Serializer.Marshal(value)
+		return data, true, err
+	}
+	rv = reflect.Indirect(rv)
+	switch rv.Kind() {
+	case reflect.String:
+		return []byte(rv.String()), true, nil
+	case reflect.Slice:
+		return rv.Bytes(), true, nil
+	}
+	return nil, false, fmt.Errorf("can not encrypt %T", value)
+}
+
+// setPlaintext 把解密得到的明文写回字段
+func (f *Field) setPlaintext(v reflect.Value, plaintext []byte) error {
+	if f.Serializer != nil {
+		ptr := reflect.New(v.Type())
+		if err := f.Serializer.Unmarshal(plaintext, ptr.Interface()); err != nil {
+			return err
+		}
+		v.Set(ptr.Elem())
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.String {
+		v.SetString(string(plaintext))
+	} else {
+		v.SetBytes(plaintext)
+	}
+	return nil
+}
+
+// encryptedValue 在写入数据库时使用当前密钥加密字段的值
+type encryptedValue struct {
+	field *Field
+	value interface{}
+}
+
+func (v encryptedValue) Value() (driver.Value, error) {
+	plaintext, ok, err := v.field.plaintext(v.value)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if v.field.Encryptor.keys == nil {
+		return nil, errors.New("encryption key provider is not set")
+	}
+	return v.field.Encryptor.Encrypt(v.field.Encryptor.keys.CurrentKeyID(), plaintext)
+}
+
+// encryptedScanner 解密数据库中的密文并写回字段，NULL 写入零值
+type encryptedScanner struct {
+	field *Field
+	v     reflect.Value
+}
+
+func (s encryptedScanner) Scan(src interface{}) error {
+	var ciphertext string
+	switch v := src.(type) {
+	case nil:
+		s.v.SetZero()
+		return nil
+	case []byte:
+		ciphertext = string(v)
+	case string:
+		ciphertext = v
+	default:
+		return fmt.Errorf("can not decrypt %T into %s", src, s.field.Name)
+	}
+	plaintext, err := s.field.Encryptor.Decrypt(ciphertext)
+	if err != nil {
+		return fmt.Errorf("decrypt %s: %w", s.field.Name, err)
+	}
+	return s.field.setPlaintext(s.v, plaintext)
+}
@@ -0,0 +1,104 @@
+package schema
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type Patient struct {
+	ID    int     `go-orm:"PRIMARY KEY"`
+	SSN   string  `go-orm:"encrypt:deterministic"`
+	Token []byte  `go-orm:"encrypt"`
+	Note  *string `go-orm:"encrypt"`
+}
+
+var testKeys = &Keyring{
+	Current: "k1",
+	Keys:    map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)},
+}
+
+func TestParse_Encrypt(t *testing.T) {
+	schema := Parse(&Patient{}, TestDial, WithKeyProvider(testKeys))
+	for _, name := range []string{"SSN", "Token", "Note"} {
+		if field := schema.GetField(name); field.Encryptor == nil || field.Type != "TEXT" {
+			t.Fatalf("failed to parse encrypted field %s", name)
+		}
+	}
+	if !schema.GetField("SSN").Encryptor.Deterministic() || schema.GetField("Token").Encryptor.Deterministic() {
+		t.Fatal("failed to parse encryption mode")
+	}
+}
+
+func TestEncryptor_RoundTrip(t *testing.T) {
+	schema := Parse(&Patient{}, TestDial, WithKeyProvider(testKeys))
+	values := schema.RecordValues(&Patient{ID: 1, SSN: "123-45-6789", Token: []byte("secret")})
+	ssn, err := values[1].(driver.Valuer).Value()
+	if err != nil || !strings.HasPrefix(ssn.(string), "k1:") || strings.Contains(ssn.(string), "123-45-6789") {
+		t.Fatal("failed to encrypt field", ssn, err)
+	}
+	if note, _ := values[3].(driver.Valuer).Value(); note != nil {
+		t.Fatal("expect NULL for nil pointer, but got", note)
+	}
+
+	// 确定性加密相同的明文得到相同的密文，随机加密每次不同
+	again, _ := values[1].(driver.Valuer).Value()
+	token1, _ := values[2].(driver.Valuer).Value()
+	token2, _ := values[2].(driver.Valuer).Value()
+	if ssn != again || token1 == token2 {
+		t.Fatal("unexpected encryption mode")
+	}
+
+	got := &Patient{}
+	dest := reflect.ValueOf(got).Elem()
+	for name, src := range map[string]interface{}{"SSN": ssn, "Token": []byte(token1.(string)), "Note": nil} {
+		scanner, _ := schema.GetField(name).Scanner(dest.FieldByName(name))
+		if err := scanner.Scan(src); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got.SSN != "123-45-6789" || string(got.Token) != "secret" || got.Note != nil {
+		t.Fatal("failed to decrypt fields", got)
+	}
+}
+
+func TestEncryptor_KeyRotation(t *testing.T) {
+	old := Parse(&Patient{}, TestDial, WithKeyProvider(testKeys))
+	ssn, _ := old.RecordValues(&Patient{SSN: "123-45-6789"})[1].(driver.Valuer).Value()
+
+	rotated := &Keyring{
+		Current: "k2",
+		Keys:    map[string][]byte{"k1": testKeys.Keys["k1"], "k2": bytes.Repeat([]byte{2}, 32)},
+	}
+	schema := Parse(&Patient{}, TestDial, WithKeyProvider(rotated))
+	got := &Patient{}
+	scanner, _ := schema.GetField("SSN").Scanner(reflect.ValueOf(got).Elem().FieldByName("SSN"))
+	if err := scanner.Scan(ssn); err != nil || got.SSN != "123-45-6789" {
+		t.Fatal("failed to decrypt with old key", err)
+	}
+	if ciphertexts, err := schema.GetField("SSN").Ciphertexts("123-45-6789"); err != nil || len(ciphertexts) != 2 {
+		t.Fatal("expect ciphertexts for every key", ciphertexts, err)
+	}
+	if _, err := schema.GetField("Token").Ciphertexts("secret"); err == nil {
+		t.Fatal("expect error for randomized encryption")
+	}
+}
+
+// 字段名和明文拼接后相同的两个字段不能使用相同的 nonce
+func TestEncryptor_NoncePerField(t *testing.T) {
+	ssn := &Encryptor{keys: testKeys, deterministic: true, aad: []byte("SSN")}
+	last4 := &Encryptor{keys: testKeys, deterministic: true, aad: []byte("SSNLast4")}
+	c1, err1 := ssn.Encrypt("k1", []byte("Last41234"))
+	c2, err2 := last4.Encrypt("k1", []byte("1234"))
+	if err1 != nil || err2 != nil {
+		t.Fatal(err1, err2)
+	}
+	n1, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(c1, "k1:"))
+	n2, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(c2, "k1:"))
+	if c1 == c2 || bytes.Equal(n1[:12], n2[:12]) {
+		t.Fatal("expect different nonces for different fields")
+	}
+}
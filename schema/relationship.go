@@ -0,0 +1,177 @@
+package schema
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"go-orm/dialect"
+	"go-orm/log"
+	"reflect"
+	"strings"
+	"time"
+)
+
+type RelationshipType string
+
+const (
+	HasOne    RelationshipType = "has_one"
+	HasMany   RelationshipType = "has_many"
+	BelongsTo RelationshipType = "belongs_to"
+	Many2Many RelationshipType = "many_to_many"
+)
+
+// Relationship 描述模型上的一个关联字段
+// BelongsTo 时 ForeignKey 是当前模型的字段，References 是关联模型的字段
+// HasOne/HasMany 时 ForeignKey 是关联模型的字段，References 是当前模型的字段
+// Many2Many 时 ForeignKey 是当前模型的字段，References 是关联模型的字段，
+// 连接表中分别用 JoinForeignKey 和 JoinReferences 两列保存它们的值
+type Relationship struct {
+	Name       string
+	Type       RelationshipType
+	FieldType  reflect.Type // 关联模型的结构体类型，例如 []*Order 对应 Order
+	ForeignKey string
+	References string
+
+	JoinTable      *Schema
+	JoinForeignKey string
+	JoinReferences string
+}
+
+func (s *Schema) GetRelationship(name string) *Relationship {
+	for _, rel := range s.Relationships {
+		if rel.Name == name {
+			return rel
+		}
+	}
+	return nil
+}
+
+var (
+	scannerType   = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuerType    = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	dataTyperType = reflect.TypeOf((*dialect.DataTyper)(nil)).Elem()
+)
+
+// relationElem 判断字段是否是关联字段，是则返回关联模型的结构体类型和是否为切片
+// time.Time 以及实现了 sql.Scanner/driver.Valuer/dialect.DataTyper 的类型按普通列处理
+func relationElem(typ reflect.Type) (elem reflect.Type, many bool, ok bool) {
+	if typ.Kind() == reflect.Slice {
+		typ, many = typ.Elem(), true
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || typ == reflect.TypeOf(time.Time{}) ||
+		reflect.PointerTo(typ).Implements(scannerType) || reflect.PointerTo(typ).Implements(valuerType) ||
+		reflect.PointerTo(typ).Implements(dataTyperType) {
+		return nil, false, false
+	}
+	return typ, many, true
+}
+
+// parseRelationship 根据外键命名规则或者 foreignKey/references 标签推断关联关系
+//   - 切片字段声明了 many2many:连接表名 时为 Many2Many
+//   - 其他切片字段为 HasMany，外键默认是关联模型上的 模型名+主键名，例如 User.Orders 对应 Order.UserID
+//   - 结构体字段，当前模型上存在 字段名+关联模型主键名 的字段时为 BelongsTo，例如 Order.User 对应 Order.UserID
+//   - 否则为 HasOne，外键规则与 HasMany 相同
+func (s *Schema) parseRelationship(p reflect.StructField, elem reflect.Type, many bool, settings map[string]string, d dialect.Dialect) *Relationship {
+	owner := reflect.Indirect(reflect.ValueOf(s.Model)).Type()
+	rel := &Relationship{Name: p.Name, FieldType: elem}
+	foreignKey, references := settings["foreignkey"], settings["references"]
+
+	if joinTable, ok := settings["many2many"]; ok && many {
+		return parseMany2Many(rel, owner, joinTable, settings, d)
+	}
+
+	if !many {
+		refs := references
+		if refs == "" {
+			refs = primaryFieldName(elem)
+		}
+		fk := foreignKey
+		if fk == "" {
+			fk = p.Name + refs
+		}
+		if _, ok := owner.FieldByName(fk); ok && refs != "" {
+			if _, ok := elem.FieldByName(refs); ok {
+				rel.Type, rel.ForeignKey, rel.References = BelongsTo, fk, refs
+				return rel
+			}
+		}
+	}
+
+	rel.Type = HasOne
+	if many {
+		rel.Type = HasMany
+	}
+	rel.References = references
+	if rel.References == "" {
+		rel.References = primaryFieldName(owner)
+	}
+	rel.ForeignKey = foreignKey
+	if rel.ForeignKey == "" {
+		rel.ForeignKey = owner.Name() + rel.References
+	}
+	_, hasFK := elem.FieldByName(rel.ForeignKey)
+	_, hasRefs := owner.FieldByName(rel.References)
+	if rel.References == "" || !hasFK || !hasRefs {
+		log.Errorf("invalid relationship %s.%s: foreign key not found", owner.Name(), p.Name)
+		return nil
+	}
+	return rel
+}
+
+// parseMany2Many 解析多对多关联，并根据两边键的类型生成连接表的 Schema
+// 连接表的列默认是 模型名+键名，例如 User.Roles 对应 user_roles(UserID, RoleID)
+func parseMany2Many(rel *Relationship, owner reflect.Type, joinTable string, settings map[string]string, d dialect.Dialect) *Relationship {
+	rel.Type = Many2Many
+	rel.ForeignKey = settings["foreignkey"]
+	if rel.ForeignKey == "" {
+		rel.ForeignKey = primaryFieldName(owner)
+	}
+	rel.References = settings["references"]
+	if rel.References == "" {
+		rel.References = primaryFieldName(rel.FieldType)
+	}
+	fk, hasFK := owner.FieldByName(rel.ForeignKey)
+	refs, hasRefs := rel.FieldType.FieldByName(rel.References)
+	if joinTable == "" || !hasFK || !hasRefs {
+		log.Errorf("invalid many2many relationship %s.%s", owner.Name(), rel.Name)
+		return nil
+	}
+
+	rel.JoinForeignKey = settings["joinforeignkey"]
+	if rel.JoinForeignKey == "" {
+		rel.JoinForeignKey = owner.Name() + rel.ForeignKey
+	}
+	rel.JoinReferences = settings["joinreferences"]
+	if rel.JoinReferences == "" {
+		rel.JoinReferences = rel.FieldType.Name() + rel.References
+	}
+	if rel.JoinForeignKey == rel.JoinReferences {
+		log.Errorf("invalid many2many relationship %s.%s: duplicate join column %s", owner.Name(), rel.Name, rel.JoinForeignKey)
+		return nil
+	}
+
+	// 连接表没有对应的 Go 类型，用 reflect.StructOf 动态构造一个只包含两列的结构体
+	joinType := reflect.StructOf([]reflect.StructField{
+		{Name: rel.JoinForeignKey, Type: fk.Type},
+		{Name: rel.JoinReferences, Type: refs.Type},
+	})
+	rel.JoinTable = Parse(reflect.New(joinType).Interface(), d)
+	rel.JoinTable.Name = joinTable
+	return rel
+}
+
+// primaryFieldName 返回结构体上 go-orm 标签声明了 PRIMARY KEY 的字段名，没有时使用 ID 字段
+func primaryFieldName(typ reflect.Type) string {
+	for i := 0; i < typ.NumField(); i++ {
+		constraint, _ := parseTag(typ.Field(i).Tag.Get("go-orm"))
+		if strings.Contains(strings.ToUpper(constraint), "PRIMARY KEY") {
+			return typ.Field(i).Name
+		}
+	}
+	if _, ok := typ.FieldByName("ID"); ok {
+		return "ID"
+	}
+	return ""
+}
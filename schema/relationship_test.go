@@ -0,0 +1,74 @@
+package schema
+
+import "testing"
+
+type Company struct {
+	ID   int `go-orm:"PRIMARY KEY"`
+	Name string
+}
+
+type Profile struct {
+	ID       int `go-orm:"PRIMARY KEY"`
+	MemberID int
+}
+
+type Order struct {
+	ID      int `go-orm:"PRIMARY KEY"`
+	OwnerID int
+}
+
+type Member struct {
+	ID        int `go-orm:"PRIMARY KEY"`
+	CompanyID int
+	Company   Company
+	Profile   *Profile
+	Orders    []Order `go-orm:"foreignKey:OwnerID"`
+}
+
+func TestParse_Relationships(t *testing.T) {
+	schema := Parse(&Member{}, TestDial)
+	if len(schema.Fields) != 2 || len(schema.Relationships) != 3 {
+		t.Fatal("association fields should not be columns, got", schema.FieldNames)
+	}
+
+	cases := []struct {
+		Name       string
+		Type       RelationshipType
+		ForeignKey string
+		References string
+	}{
+		{"Company", BelongsTo, "CompanyID", "ID"},
+		{"Profile", HasOne, "MemberID", "ID"},
+		{"Orders", HasMany, "OwnerID", "ID"},
+	}
+	for _, c := range cases {
+		rel := schema.GetRelationship(c.Name)
+		if rel == nil || rel.Type != c.Type || rel.ForeignKey != c.ForeignKey || rel.References != c.References {
+			t.Fatalf("failed to parse relationship %s, got %+v", c.Name, rel)
+		}
+	}
+}
+
+type Role struct {
+	Code string `go-orm:"PRIMARY KEY"`
+}
+
+type Staff struct {
+	ID    int    `go-orm:"PRIMARY KEY"`
+	Roles []Role `go-orm:"many2many:staff_roles"`
+}
+
+func TestParse_Many2Many(t *testing.T) {
+	schema := Parse(&Staff{}, TestDial)
+	rel := schema.GetRelationship("Roles")
+	if rel == nil || rel.Type != Many2Many || rel.ForeignKey != "ID" || rel.References != "Code" {
+		t.Fatalf("failed to parse many2many relationship, got %+v", rel)
+	}
+	join := rel.JoinTable
+	if join.Name != "staff_roles" || len(join.Fields) != 2 {
+		t.Fatal("failed to parse join table")
+	}
+	if join.GetField("StaffID").Type != "INT" || join.GetField("RoleCode").Type != "VARCHAR(255)" {
+		t.Fatal("failed to parse join table columns")
+	}
+}
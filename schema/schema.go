@@ -1,16 +1,34 @@
 package schema
 
 import (
+	"database/sql"
 	"go-orm/dialect"
+	"go-orm/log"
 	"go/ast"
 	"reflect"
+	"strings"
+	"time"
 )
 
 // Field represents a column of table
 type Field struct {
 	Name string
 	Type string
-	Tag  string
+	// Tag 是建表时附加在列定义后面的约束，例如 PRIMARY KEY
+	Tag string
+	// Settings 是 go-orm 标签中除列约束外的配置项，例如 version
+	Settings map[string]string
+	// AutoCreateTime 和 AutoUpdateTime 表示插入、更新时自动写入当前时间
+	AutoCreateTime bool
+	AutoUpdateTime bool
+	// Nullable 表示列可以为 NULL，指针和 sql.Null* 类型的字段为 true，其他字段建表时为 NOT NULL
+	Nullable bool
+	// Serializer 是 serializer 标签指定的序列化方式，写入时编码，读取时解码，没有则为 nil
+	Serializer Serializer
+	// Encryptor 加密 encrypt 标签声明的字段，没有则为 nil
+	Encryptor *Encryptor
+	// timeUnit 是整数时间戳字段的精度，time.Time 字段为空
+	timeUnit string
 }
 
 // Schema represents a table of database
@@ -20,6 +38,33 @@ type Schema struct {
 	Fields     []*Field
 	FieldNames []string
 	FieldsMap  map[string]*Field
+	// PrimaryField 是 Tag 中声明了 PRIMARY KEY 的字段，没有则为 nil
+	PrimaryField *Field
+	// VersionField 是声明了 version 的整数字段，用于乐观锁，没有则为 nil
+	VersionField *Field
+	// DeletedAtField 是类型为 *time.Time 或 sql.NullTime 的 DeletedAt 字段，用于软删除，没有则为 nil
+	DeletedAtField *Field
+	// Relationships 是模型上的关联字段，这些字段不会映射为列
+	Relationships []*Relationship
+}
+
+// Constraint 返回建表时附加在列类型后面的约束，不可为 NULL 且没有声明 NULL/NOT NULL 的字段补充 NOT NULL
+func (f *Field) Constraint() string {
+	if f.Nullable || strings.Contains(strings.ToUpper(f.Tag), "NULL") {
+		return f.Tag
+	}
+	return strings.TrimSpace("NOT NULL " + f.Tag)
+}
+
+// Scanner 返回扫描加密字段和声明了 serializer 的字段时使用的 sql.Scanner，v 是可寻址的字段，其他字段返回 false
+func (f *Field) Scanner(v reflect.Value) (sql.Scanner, bool) {
+	if f.Encryptor != nil {
+		return encryptedScanner{field: f, v: v}, true
+	}
+	if f.Serializer == nil {
+		return nil, false
+	}
+	return serializedScanner{serializer: f.Serializer, field: v}, true
 }
 
 func (s *Schema) GetField(name string) *Field {
@@ -32,13 +77,49 @@ func (s *Schema) RecordValues(dest interface{}) []interface{} {
 	destValue := reflect.Indirect(reflect.ValueOf(dest))
 	var fieldValues []interface{}
 	for _, field := range s.Fields {
-		fieldValues = append(fieldValues, destValue.FieldByName(field.Name).Interface())
+		fieldValues = append(fieldValues, field.ValueOf(destValue.FieldByName(field.Name)))
 	}
 	return fieldValues
 }
 
+// ValueOf 返回字段写入数据库时使用的值，v 是字段的值
+// 加密字段在写入时加密，声明了 serializer 的字段在写入时编码，只有指针实现了 driver.Valuer 的类型返回指向副本的指针，否则 database/sql 不会调用 Value 方法
+func (f *Field) ValueOf(v reflect.Value) interface{} {
+	if f.Encryptor != nil {
+		return encryptedValue{field: f, value: v.Interface()}
+	}
+	if f.Serializer != nil {
+		return serializedValue{serializer: f.Serializer, value: v.Interface()}
+	}
+	if v.Type().Implements(valuerType) || !reflect.PointerTo(v.Type()).Implements(valuerType) {
+		return v.Interface()
+	}
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+	return ptr.Interface()
+}
+
+// Option 是解析模型时使用的 Engine 级别配置
+type Option func(*config)
+
+type config struct {
+	keys KeyProvider
+}
+
+// WithKeyProvider 设置加密字段使用的密钥
+func WithKeyProvider(keys KeyProvider) Option {
+	return func(c *config) {
+		c.keys = keys
+	}
+}
+
 // Parse 将任意对象解析成schema实例
-func Parse(dest interface{}, d dialect.Dialect) *Schema {
+func Parse(dest interface{}, d dialect.Dialect, opts ...Option) *Schema {
+	var conf config
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
 	// TypeOf() 和 ValueOf() 是 reflect 包最常用 2 个方法，分别用来返回入参的类型和值。
 	// 因为设计的入参是一个对象的指针，因此需要 reflect.Indirect() 获取指针指向的实例
 	// Type()：获取解引用后值的类型。
@@ -54,15 +135,56 @@ func Parse(dest interface{}, d dialect.Dialect) *Schema {
 		p := modelType.Field(i)
 		// 只处理非匿名和导出字段
 		if !p.Anonymous && ast.IsExported(p.Name) {
-			field := &Field{
-				Name: p.Name,
-				Type: d.DataTypeOf(reflect.Indirect(reflect.New(p.Type))),
-			}
-
-			// 查找字段标签中是否存在 go-orm 标签，如果存在则将其值赋值给 Field 的 Tag 字段
+			field := &Field{Name: p.Name}
+			// 查找字段标签中是否存在 go-orm 标签，拆分成列约束和配置项
 			v, ok := p.Tag.Lookup("go-orm")
 			if ok {
-				field.Tag = v
+				field.Tag, field.Settings = parseTag(v)
+			}
+
+			if name, ok := field.Settings["serializer"]; ok {
+				if field.Serializer, ok = GetSerializer(name); !ok {
+					log.Errorf("serializer %s not found for %s.%s", name, schema.Name, p.Name)
+				}
+			}
+
+			// 关联字段不是表中的列，序列化的结构体字段除外
+			if elem, many, ok := relationElem(p.Type); ok && field.Serializer == nil {
+				if rel := schema.parseRelationship(p, elem, many, field.Settings, d); rel != nil {
+					schema.Relationships = append(schema.Relationships, rel)
+				}
+				continue
+			}
+
+			if mode, ok := field.Settings["encrypt"]; ok {
+				if canEncrypt(p.Type, field.Serializer) {
+					field.Encryptor = &Encryptor{keys: conf.keys, deterministic: mode == "deterministic", aad: []byte(p.Name)}
+				} else {
+					log.Errorf("can not encrypt %s.%s of type %s", schema.Name, p.Name, p.Type)
+				}
+			}
+
+			if field.Encryptor != nil {
+				field.Type = "TEXT" // 密文是 base64 编码的字符串
+			} else if field.Serializer != nil {
+				field.Type = serializedDataType(field.Serializer, d)
+			} else {
+				col, err := columnType(field.Settings)
+				if err != nil {
+					log.Errorf("%s.%s: %v", schema.Name, p.Name, err)
+				}
+				field.Type = d.ColumnTypeOf(reflect.Indirect(reflect.New(p.Type)), col)
+			}
+			_, field.Nullable = dialect.NullableElem(p.Type)
+			if isDeletedAt(p) {
+				schema.DeletedAtField = field
+			}
+			if _, ok := field.Settings["version"]; ok && isInteger(p.Type.Kind()) {
+				schema.VersionField = field
+			}
+			parseAutoTime(field, p)
+			if schema.PrimaryField == nil && strings.Contains(strings.ToUpper(field.Tag), "PRIMARY KEY") {
+				schema.PrimaryField = field
 			}
 			// 更新 Schema对象的Field相关自动
 			schema.Fields = append(schema.Fields, field)
@@ -72,3 +194,18 @@ func Parse(dest interface{}, d dialect.Dialect) *Schema {
 	}
 	return schema
 }
+
+func isInteger(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// isDeletedAt 判断字段是否是软删除字段
+func isDeletedAt(p reflect.StructField) bool {
+	return p.Name == "DeletedAt" &&
+		(p.Type == reflect.TypeOf(&time.Time{}) || p.Type == reflect.TypeOf(sql.NullTime{}))
+}
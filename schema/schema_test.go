@@ -1,8 +1,12 @@
 package schema
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
 	"go-orm/dialect"
 	"testing"
+	"time"
 )
 
 type User struct {
@@ -45,3 +49,124 @@ func TestSchema_TableName(t *testing.T) {
 		t.Fatal("failed to parse User struct")
 	}
 }
+
+type Document struct {
+	ID      int `go-orm:"PRIMARY KEY"`
+	Version int `go-orm:"version"`
+}
+
+func TestParse_Settings(t *testing.T) {
+	schema := Parse(&Document{}, TestDial)
+	if schema.PrimaryField != schema.GetField("ID") || schema.GetField("ID").Tag != "PRIMARY KEY" {
+		t.Fatal("failed to parse constraint from tag")
+	}
+	if schema.VersionField != schema.GetField("Version") {
+		t.Fatal("failed to parse version field")
+	}
+}
+
+type Customer struct {
+	Name      string
+	DeletedAt *time.Time
+}
+
+func TestParse_DeletedAt(t *testing.T) {
+	schema := Parse(&Customer{}, TestDial)
+	field := schema.GetField("DeletedAt")
+	if schema.DeletedAtField != field || field.Type != "DATETIME" {
+		t.Fatal("failed to parse soft delete field")
+	}
+}
+
+type Subscriber struct {
+	ID       int `go-orm:"PRIMARY KEY"`
+	Nickname *string
+	Email    sql.NullString
+	Score    sql.Null[int64]
+	Bio      string `go-orm:"NULL"`
+}
+
+func TestParse_Nullable(t *testing.T) {
+	schema := Parse(&Subscriber{}, TestDial)
+	cases := []struct {
+		Name       string
+		Type       string
+		Constraint string
+	}{
+		{"ID", "INT", "NOT NULL PRIMARY KEY"},
+		{"Nickname", "VARCHAR(255)", ""},
+		{"Email", "VARCHAR(255)", ""},
+		{"Score", "BIGINT", ""},
+		{"Bio", "VARCHAR(255)", "NULL"},
+	}
+	for _, c := range cases {
+		field := schema.GetField(c.Name)
+		if field.Type != c.Type || field.Constraint() != c.Constraint {
+			t.Fatalf("expect %s %s for %s, but got %s %s", c.Type, c.Constraint, c.Name, field.Type, field.Constraint())
+		}
+	}
+}
+
+type Money struct {
+	Cents int64
+}
+
+func (m *Money) Value() (driver.Value, error) {
+	return m.Cents, nil
+}
+
+func (m *Money) Scan(src interface{}) error {
+	cents, ok := src.(int64)
+	if !ok {
+		return fmt.Errorf("can not scan %T into Money", src)
+	}
+	m.Cents = cents
+	return nil
+}
+
+type Invoice struct {
+	ID    int `go-orm:"PRIMARY KEY"`
+	Total Money
+}
+
+func TestParse_CustomType(t *testing.T) {
+	schema := Parse(&Invoice{}, TestDial)
+	if len(schema.Relationships) != 0 || schema.GetField("Total").Type != "BIGINT" {
+		t.Fatal("failed to parse custom type as column")
+	}
+	values := schema.RecordValues(&Invoice{ID: 1, Total: Money{100}})
+	valuer, ok := values[1].(driver.Valuer)
+	if !ok {
+		t.Fatalf("expect driver.Valuer, but got %T", values[1])
+	}
+	if v, _ := valuer.Value(); v != int64(100) {
+		t.Fatal("failed to get custom type value", v)
+	}
+}
+
+type Product struct {
+	ID      uint64    `go-orm:"PRIMARY KEY"`
+	Code    string    `go-orm:"size:32"`
+	Price   float64   `go-orm:"precision:10;scale:2"`
+	Status  string    `go-orm:"enum:draft,published"`
+	Created time.Time `go-orm:"precision:3"`
+}
+
+func TestParse_ColumnType(t *testing.T) {
+	schema := Parse(&Product{}, TestDial)
+	cases := map[string]string{
+		"ID":      "BIGINT UNSIGNED",
+		"Code":    "VARCHAR(32)",
+		"Price":   "DECIMAL(10,2)",
+		"Status":  "ENUM('draft','published')",
+		"Created": "DATETIME(3)",
+	}
+	for name, typ := range cases {
+		if field := schema.GetField(name); field.Type != typ {
+			t.Fatalf("expect %s for %s, but got %s", typ, name, field.Type)
+		}
+	}
+	if schema.GetField("Code").Tag != "" {
+		t.Fatal("expect size not to be a constraint")
+	}
+}
@@ -0,0 +1,130 @@
+package schema
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"go-orm/dialect"
+	"reflect"
+	"sync"
+)
+
+// Serializer 负责字段值和数据库中保存的数据之间的转换，用于 map、切片、结构体等没有对应列类型的字段
+// 通过 `go-orm:"serializer:json"` 标签为字段指定序列化方式
+type Serializer interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var serializers sync.Map // name -> Serializer
+
+func init() {
+	RegisterSerializer("json", JSONSerializer{})
+	RegisterSerializer("gob", GobSerializer{})
+}
+
+// RegisterSerializer 注册序列化方式，可以覆盖内置的 json 和 gob
+// 序列化的列默认使用 BLOB 类型，Serializer 实现了 dialect.DataTyper 时使用它声明的列类型
+func RegisterSerializer(name string, serializer Serializer) {
+	serializers.Store(name, serializer)
+}
+
+func GetSerializer(name string) (Serializer, bool) {
+	serializer, ok := serializers.Load(name)
+	if !ok {
+		return nil, false
+	}
+	return serializer.(Serializer), true
+}
+
+// JSONSerializer 使用 encoding/json 序列化，数据库支持原生 JSON 类型时使用 JSON 列，否则使用 TEXT 列
+type JSONSerializer struct{}
+
+func (JSONSerializer) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONSerializer) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// GobSerializer 使用 encoding/gob 序列化，使用 BLOB 列
+type GobSerializer struct{}
+
+func (GobSerializer) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobSerializer) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// serializedDataType 返回序列化字段的列类型
+func serializedDataType(serializer Serializer, d dialect.Dialect) string {
+	if t, ok := serializer.(dialect.DataTyper); ok {
+		return t.OrmDataType()
+	}
+	if _, ok := serializer.(JSONSerializer); ok {
+		if j, ok := dialect.Unwrap(d).(dialect.JSONer); ok {
+			return j.JSONDataType()
+		}
+		return "TEXT"
+	}
+	return d.DataTypeOf(reflect.ValueOf([]byte(nil)))
+}
+
+// serializedValue 在写入数据库时才编码字段的值，Changes 比较的仍然是字段原本的值
+type serializedValue struct {
+	serializer Serializer
+	value      interface{}
+}
+
+func (v serializedValue) Value() (driver.Value, error) {
+	// 指针字段为 nil 时写入 NULL
+	if rv := reflect.ValueOf(v.value); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, nil
+	}
+	data, err := v.serializer.Marshal(v.value)
+	if err != nil {
+		return nil, err
+	}
+	// MySQL 不接受二进制字符集的字符串作为 JSON 列的值
+	if _, ok := v.serializer.(JSONSerializer); ok {
+		return string(data), nil
+	}
+	return data, nil
+}
+
+// serializedScanner 读取数据库中的数据并解码到字段上，NULL 解码为零值
+type serializedScanner struct {
+	serializer Serializer
+	field      reflect.Value
+}
+
+func (s serializedScanner) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		s.field.SetZero()
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("can not unmarshal %T into %s", src, s.field.Type())
+	}
+	// 先解码到新的值上，避免 map 等字段残留上一次的数据
+	ptr := reflect.New(s.field.Type())
+	if err := s.serializer.Unmarshal(data, ptr.Interface()); err != nil {
+		return err
+	}
+	s.field.Set(ptr.Elem())
+	return nil
+}
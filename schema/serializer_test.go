@@ -0,0 +1,60 @@
+package schema
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+type Preference struct {
+	Theme string
+}
+
+type Setting struct {
+	ID      int               `go-orm:"PRIMARY KEY"`
+	Labels  map[string]string `go-orm:"serializer:json"`
+	Pref    *Preference       `go-orm:"serializer:json"`
+	History []int             `go-orm:"serializer:gob"`
+}
+
+func TestParse_Serializer(t *testing.T) {
+	schema := Parse(&Setting{}, TestDial)
+	if len(schema.Relationships) != 0 || len(schema.Fields) != 4 {
+		t.Fatal("failed to parse serializer fields as columns")
+	}
+	cases := map[string]string{"Labels": "JSON", "Pref": "JSON", "History": "BLOB"}
+	for name, typ := range cases {
+		if field := schema.GetField(name); field.Type != typ || field.Serializer == nil {
+			t.Fatalf("expect %s for %s, but got %s", typ, name, field.Type)
+		}
+	}
+}
+
+func TestSerializer_RoundTrip(t *testing.T) {
+	schema := Parse(&Setting{}, TestDial)
+	setting := &Setting{ID: 1, Labels: map[string]string{"env": "prod"}, History: []int{1, 2}}
+	values := schema.RecordValues(setting)
+
+	labels, _ := values[1].(driver.Valuer).Value()
+	if labels != `{"env":"prod"}` {
+		t.Fatal("failed to marshal json field", labels)
+	}
+	if pref, _ := values[2].(driver.Valuer).Value(); pref != nil {
+		t.Fatal("expect NULL for nil pointer, but got", pref)
+	}
+	history, _ := values[3].(driver.Valuer).Value()
+
+	got := &Setting{}
+	dest := reflect.ValueOf(got).Elem()
+	scanner, _ := schema.GetField("Labels").Scanner(dest.FieldByName("Labels"))
+	if err := scanner.Scan([]byte(labels.(string))); err != nil {
+		t.Fatal(err)
+	}
+	scanner, _ = schema.GetField("History").Scanner(dest.FieldByName("History"))
+	if err := scanner.Scan(history); err != nil {
+		t.Fatal(err)
+	}
+	if got.Labels["env"] != "prod" || !reflect.DeepEqual(got.History, []int{1, 2}) {
+		t.Fatal("failed to unmarshal serializer fields", got)
+	}
+}
@@ -0,0 +1,68 @@
+package schema
+
+import (
+	"fmt"
+	"go-orm/dialect"
+	"strconv"
+	"strings"
+)
+
+// settingKeys 是 go-orm 标签中可以识别的配置项，其余内容会作为列约束原样保留
+var settingKeys = map[string]bool{
+	"version":        true,
+	"autocreatetime": true,
+	"autoupdatetime": true,
+	"foreignkey":     true,
+	"references":     true,
+	"many2many":      true,
+	"joinforeignkey": true,
+	"joinreferences": true,
+	"serializer":     true,
+	"encrypt":        true,
+	"size":           true,
+	"precision":      true,
+	"scale":          true,
+	"enum":           true,
+}
+
+// parseTag 将 go-orm 标签按 ; 拆分
+// 形如 key 或 key:value 的已知配置项放入 settings（key 统一小写），其余部分拼接成列约束
+// 例如 `go-orm:"PRIMARY KEY;version"` 得到约束 PRIMARY KEY 和配置项 version
+func parseTag(tag string) (constraint string, settings map[string]string) {
+	settings = make(map[string]string)
+	var constraints []string
+	for _, part := range strings.Split(tag, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(part, ":")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if settingKeys[key] {
+			settings[key] = strings.TrimSpace(value)
+			continue
+		}
+		constraints = append(constraints, part)
+	}
+	return strings.Join(constraints, " "), settings
+}
+
+// columnType 从配置项中读取 size、precision、scale 和 enum，enum 的取值用 , 分隔
+// 例如 `go-orm:"size:64"`、`go-orm:"precision:10;scale:2"`、`go-orm:"enum:draft,published"`
+func columnType(settings map[string]string) (col dialect.ColumnType, err error) {
+	for key, dest := range map[string]*int{"size": &col.Size, "precision": &col.Precision, "scale": &col.Scale} {
+		value, ok := settings[key]
+		if !ok {
+			continue
+		}
+		if *dest, err = strconv.Atoi(value); err != nil || *dest < 0 {
+			return col, fmt.Errorf("invalid %s %q", key, value)
+		}
+	}
+	if enum, ok := settings["enum"]; ok {
+		for _, v := range strings.Split(enum, ",") {
+			col.Enum = append(col.Enum, strings.TrimSpace(v))
+		}
+	}
+	return col, nil
+}
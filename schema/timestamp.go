@@ -0,0 +1,47 @@
+package schema
+
+import (
+	"reflect"
+	"time"
+)
+
+// parseAutoTime 识别自动时间戳字段
+// 名为 CreatedAt/UpdatedAt 的 time.Time 或整数字段默认开启
+// 也可以通过 autoCreateTime/autoUpdateTime 标签开启，整数字段可以指定精度，例如 autoUpdateTime:milli
+func parseAutoTime(field *Field, p reflect.StructField) {
+	isTime := p.Type == reflect.TypeOf(time.Time{})
+	if !isTime && !isInteger(p.Type.Kind()) {
+		return
+	}
+	unit, ok := field.Settings["autocreatetime"]
+	if ok || p.Name == "CreatedAt" {
+		field.AutoCreateTime = true
+	}
+	if v, ok := field.Settings["autoupdatetime"]; ok || p.Name == "UpdatedAt" {
+		field.AutoUpdateTime = true
+		if unit == "" {
+			unit = v
+		}
+	}
+	if !isTime {
+		field.timeUnit = unit
+		if unit == "" {
+			field.timeUnit = "second"
+		}
+	}
+}
+
+// TimeValue 按照字段的类型和精度把 now 转换成需要写入的值
+// time.Time 字段返回 now 本身，整数字段返回对应精度的 Unix 时间戳
+func (f *Field) TimeValue(now time.Time) interface{} {
+	switch f.timeUnit {
+	case "":
+		return now
+	case "milli":
+		return now.UnixMilli()
+	case "nano":
+		return now.UnixNano()
+	default:
+		return now.Unix()
+	}
+}
@@ -0,0 +1,34 @@
+package schema
+
+import (
+	"testing"
+	"time"
+)
+
+type Article struct {
+	Title     string
+	CreatedAt time.Time
+	UpdatedAt int64 `go-orm:"autoUpdateTime:milli"`
+	PublishAt int64 `go-orm:"autoCreateTime"`
+}
+
+func TestParse_AutoTime(t *testing.T) {
+	schema := Parse(&Article{}, TestDial)
+	now := time.Unix(1700000000, 123456789)
+
+	created := schema.GetField("CreatedAt")
+	if !created.AutoCreateTime || created.AutoUpdateTime || created.TimeValue(now) != now {
+		t.Fatal("failed to parse CreatedAt")
+	}
+	updated := schema.GetField("UpdatedAt")
+	if !updated.AutoUpdateTime || updated.TimeValue(now) != now.UnixMilli() {
+		t.Fatal("failed to parse autoUpdateTime:milli")
+	}
+	publish := schema.GetField("PublishAt")
+	if !publish.AutoCreateTime || publish.TimeValue(now) != now.Unix() {
+		t.Fatal("failed to parse autoCreateTime")
+	}
+	if schema.GetField("Title").AutoCreateTime || schema.GetField("Title").AutoUpdateTime {
+		t.Fatal("unexpected auto time field")
+	}
+}
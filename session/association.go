@@ -0,0 +1,139 @@
+package session
+
+import (
+	"go-orm/schema"
+	"reflect"
+)
+
+// insertWithAssociations 逐条插入对象及其关联记录，全部操作在同一个事务中完成
+// 先保存 BelongsTo 关联以获得外键，再插入对象本身，最后插入 HasOne/HasMany 关联记录
+func (s *Session) insertWithAssociations(values ...interface{}) (affected int64, err error) {
+	err = s.withTransaction(func() error {
+		for _, value := range values {
+			if err := s.model(value).saveBelongsTo(value); err != nil {
+				return err
+			}
+			n, err := s.model(value).insert(value)
+			if err != nil {
+				return err
+			}
+			affected += n
+			if err := s.model(value).saveHasAssociations(value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// saveWithAssociations 在同一个事务中更新对象及其关联记录
+func (s *Session) saveWithAssociations(value interface{}) (affected int64, err error) {
+	err = s.withTransaction(func() error {
+		if err := s.model(value).saveBelongsTo(value); err != nil {
+			return err
+		}
+		n, err := s.model(value).save(value)
+		if err != nil {
+			return err
+		}
+		affected = n
+		return s.model(value).saveHasAssociations(value)
+	})
+	return
+}
+
+// saveBelongsTo 插入主键为零值的 BelongsTo 关联记录，并把关联记录的键写入当前对象的外键字段
+func (s *Session) saveBelongsTo(value interface{}) error {
+	dest := reflect.Indirect(reflect.ValueOf(value))
+	for _, rel := range s.RefTable().Relationships {
+		if rel.Type != schema.BelongsTo {
+			continue
+		}
+		for _, related := range associated(dest.FieldByName(rel.Name)) {
+			c := s.clone()
+			if c.model(related).isNewRecord(related) {
+				if _, err := c.Insert(related); err != nil {
+					return err
+				}
+			}
+			setField(dest.FieldByName(rel.ForeignKey), reflect.Indirect(reflect.ValueOf(related)).FieldByName(rel.References))
+		}
+	}
+	return nil
+}
+
+// saveHasAssociations 把当前对象的键写入 HasOne/HasMany 关联记录的外键字段后保存这些记录
+// 主键为零值的记录被插入，已经有主键的记录通过 Save 更新，插入对象时也是如此
+// Many2Many 关联只插入主键为零值的记录，并在连接表中写入关联
+func (s *Session) saveHasAssociations(value interface{}) error {
+	dest := reflect.Indirect(reflect.ValueOf(value))
+	for _, rel := range s.RefTable().Relationships {
+		if rel.Type == schema.Many2Many {
+			a := &Association{session: s, owner: value, rel: rel}
+			if err := a.link(associated(dest.FieldByName(rel.Name))); err != nil {
+				return err
+			}
+			continue
+		}
+		if rel.Type != schema.HasOne && rel.Type != schema.HasMany {
+			continue
+		}
+		refs := dest.FieldByName(rel.References)
+		for _, related := range associated(dest.FieldByName(rel.Name)) {
+			setField(reflect.Indirect(reflect.ValueOf(related)).FieldByName(rel.ForeignKey), refs)
+			c := s.clone()
+			var err error
+			if c.model(related).isNewRecord(related) {
+				_, err = c.Insert(related)
+			} else {
+				_, err = c.Save(related)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isNewRecord 对象没有主键或者主键为零值时，认为是还没有插入的新记录
+func (s *Session) isNewRecord(value interface{}) bool {
+	pk := s.RefTable().PrimaryField
+	return pk == nil || reflect.Indirect(reflect.ValueOf(value)).FieldByName(pk.Name).IsZero()
+}
+
+// associated 返回关联字段中所有非空记录的指针
+func associated(fv reflect.Value) []interface{} {
+	var records []interface{}
+	add := func(v reflect.Value) {
+		if v.Kind() == reflect.Ptr {
+			if !v.IsNil() {
+				records = append(records, v.Interface())
+			}
+		} else if !v.IsZero() && v.CanAddr() {
+			records = append(records, v.Addr().Interface())
+		}
+	}
+	if fv.Kind() == reflect.Slice {
+		for i := 0; i < fv.Len(); i++ {
+			add(fv.Index(i))
+		}
+	} else {
+		add(fv)
+	}
+	return records
+}
+
+// setField 把 src 的值写入 dst，两者类型不同但可以转换时自动转换，例如 int 和 int64
+func setField(dst, src reflect.Value) {
+	if !dst.CanSet() || !src.IsValid() {
+		return
+	}
+	if src.Type() != dst.Type() && src.Type().ConvertibleTo(dst.Type()) {
+		src = src.Convert(dst.Type())
+	}
+	if src.Type() == dst.Type() {
+		dst.Set(src)
+	}
+}
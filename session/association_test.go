@@ -0,0 +1,150 @@
+package session
+
+import "testing"
+
+type Team struct {
+	ID   int `go-orm:"PRIMARY KEY AUTO_INCREMENT"`
+	Name string
+}
+
+type Item struct {
+	ID       int `go-orm:"PRIMARY KEY AUTO_INCREMENT"`
+	PlayerID int
+	Name     string
+}
+
+type Player struct {
+	ID     int `go-orm:"PRIMARY KEY AUTO_INCREMENT"`
+	Name   string
+	TeamID int
+	Team   *Team
+	Items  []Item
+}
+
+func testAssociationInit(t *testing.T) *Session {
+	t.Helper()
+	s := NewTestSession()
+	for _, model := range []interface{}{&Team{}, &Item{}, &Player{}} {
+		_ = s.Model(model).DropTable()
+		if err := s.Model(model).CreateTable(); err != nil {
+			t.Fatal("failed to create table", err)
+		}
+	}
+	return s
+}
+
+func TestSession_InsertAssociations(t *testing.T) {
+	s := testAssociationInit(t)
+	player := &Player{
+		Name:  "Tom",
+		Team:  &Team{Name: "Red"},
+		Items: []Item{{Name: "sword"}, {Name: "shield"}},
+	}
+	if _, err := s.Insert(player); err != nil {
+		t.Fatal("failed to insert with associations", err)
+	}
+	if player.ID == 0 || player.Team.ID == 0 || player.TeamID != player.Team.ID {
+		t.Fatal("failed to save belongs to association, got", player)
+	}
+
+	var items []Item
+	_ = s.Where("PlayerID = ?", player.ID).Find(&items)
+	if len(items) != 2 {
+		t.Fatal("failed to save has many association, got", items)
+	}
+}
+
+// 已经有主键的关联记录通过 Save 更新，不会重复插入
+func TestSession_InsertExistingAssociations(t *testing.T) {
+	s := testAssociationInit(t)
+	sword := &Item{Name: "sword"}
+	if _, err := s.Insert(sword); err != nil {
+		t.Fatal(err)
+	}
+
+	player := &Player{Name: "Tom", Items: []Item{*sword, {Name: "shield"}}}
+	if _, err := s.Insert(player); err != nil {
+		t.Fatal("failed to insert with existing association", err)
+	}
+	var items []Item
+	_ = s.Where("PlayerID = ?", player.ID).Find(&items)
+	if count, _ := s.Model(&Item{}).Count(); count != 2 || len(items) != 2 {
+		t.Fatal("expect existing item linked instead of inserted again, got", items)
+	}
+}
+
+type Tag struct {
+	ID   int `go-orm:"PRIMARY KEY AUTO_INCREMENT"`
+	Name string
+}
+
+type Article struct {
+	ID    int `go-orm:"PRIMARY KEY AUTO_INCREMENT"`
+	Title string
+	Tags  []*Tag `go-orm:"many2many:article_tags"`
+}
+
+func TestSession_Association(t *testing.T) {
+	s := NewTestSession()
+	_, _ = s.Raw("DROP TABLE IF EXISTS article_tags").Exec()
+	for _, model := range []interface{}{&Tag{}, &Article{}} {
+		_ = s.Model(model).DropTable()
+		_ = s.Model(model).CreateTable()
+	}
+
+	article := &Article{Title: "orm", Tags: []*Tag{{Name: "go"}}}
+	if _, err := s.Insert(article); err != nil {
+		t.Fatal("failed to insert many2many association", err)
+	}
+
+	db := &Tag{Name: "database"}
+	sql := &Tag{Name: "sql"}
+	if err := s.Association(article, "Tags").Append(db, sql); err != nil || len(article.Tags) != 3 {
+		t.Fatal("failed to append association", err)
+	}
+	if count, _ := s.Association(article, "Tags").Count(); count != 3 {
+		t.Fatal("expect 3 tags, but got", count)
+	}
+
+	_ = s.Association(article, "Tags").Delete(db)
+	if count, _ := s.Association(article, "Tags").Count(); count != 2 || len(article.Tags) != 2 {
+		t.Fatal("failed to delete association")
+	}
+
+	_ = s.Association(article, "Tags").Replace(db)
+	if count, _ := s.Association(article, "Tags").Count(); count != 1 || article.Tags[0] != db {
+		t.Fatal("failed to replace association")
+	}
+
+	_ = s.Association(article, "Tags").Clear()
+	if count, _ := s.Association(article, "Tags").Count(); count != 0 || len(article.Tags) != 0 {
+		t.Fatal("failed to clear association")
+	}
+}
+
+func TestSession_AssociationOwner(t *testing.T) {
+	s := NewTestSession()
+	_, _ = s.Raw("DROP TABLE IF EXISTS article_tags").Exec()
+	for _, model := range []interface{}{&Tag{}, &Article{}} {
+		_ = s.Model(model).DropTable()
+		_ = s.Model(model).CreateTable()
+	}
+
+	if err := s.Association(&Article{Title: "draft"}, "Tags").Append(&Tag{Name: "go"}); err == nil {
+		t.Fatal("expect error when owner has no key")
+	}
+	if count, _ := s.Model(&Tag{}).Count(); count != 0 {
+		t.Fatal("expect no tag inserted, but got", count)
+	}
+
+	article := &Article{Title: "orm"}
+	_, _ = s.Insert(article)
+	_ = s.Association(article, "Tags").Append(&Tag{Name: "go"})
+	value := *article
+	if err := s.Association(value, "Tags").Replace(&Tag{Name: "sql"}); err != nil {
+		t.Fatal("failed to replace association of non-pointer owner", err)
+	}
+	if err := s.Association(value, "Tags").Clear(); err != nil {
+		t.Fatal("failed to clear association of non-pointer owner", err)
+	}
+}
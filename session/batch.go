@@ -0,0 +1,78 @@
+package session
+
+import (
+	"errors"
+	"reflect"
+)
+
+// BatchFunc 处理一批记录，tx 是处理这一批记录时使用的 Session，batch 从 1 开始
+type BatchFunc func(tx *Session, batch int) error
+
+// BatchInTransaction 使 FindInBatches 的每一批回调都在独立的事务中执行
+// Session 已经处于事务中时，每一批使用一个保存点
+func (s *Session) BatchInTransaction() *Session {
+	s.batchTx = true
+	return s
+}
+
+// FindInBatches 按照主键从小到大分批读取满足条件的记录，每一批最多 batchSize 条，放入 dest 后调用 fn
+// 使用 WHERE pk > 上一批最后的主键 进行分页，而不是 OFFSET，因此表很大时依然高效
+// fn 返回错误时立即停止，返回值是已经成功处理的批数
+// Where、Select、Unscoped 和 Preload 对每一批都生效，OrderBy 被忽略
+//
+//	s.Where("Age > ?", 18).FindInBatches(&users, 1000, func(tx *Session, batch int) error { ... })
+func (s *Session) FindInBatches(dest interface{}, batchSize int, fn BatchFunc) (int, error) {
+	destSlice := reflect.Indirect(reflect.ValueOf(dest))
+	table := s.model(reflect.New(destSlice.Type().Elem()).Elem().Interface()).RefTable()
+	pk := table.PrimaryField
+	if pk == nil {
+		s.Clear()
+		return 0, errors.New("primary key not found")
+	}
+	if batchSize <= 0 {
+		s.Clear()
+		return 0, errors.New("batch size must be positive")
+	}
+
+	// 每一批查询都会清空语句状态，提前保存调用方设置的条件、Select 的列和预加载
+	st, inTx := s.saveStatement(), s.batchTx
+	s.Clear()
+
+	var last interface{}
+	for batch := 1; ; batch++ {
+		s.restoreStatement(st)
+		s.orders = nil
+		if last != nil {
+			s.Where(s.quote(pk.Name)+" > ?", last)
+		}
+		destSlice.Set(reflect.MakeSlice(destSlice.Type(), 0, batchSize))
+		// 每一批的对象不记录快照，避免内存随已处理的记录数增长
+		if err := s.OrderBy(pk.Name+" ASC").Limit(batchSize).find(dest, false); err != nil {
+			return batch - 1, err
+		}
+		if destSlice.Len() == 0 {
+			return batch - 1, nil
+		}
+
+		// 在回调之前记录这一批的大小和最后的主键，回调可能会修改 dest
+		size := destSlice.Len()
+		last = destSlice.Index(size - 1).FieldByName(pk.Name).Interface()
+
+		c := s.clone()
+		var err error
+		if inTx {
+			_, err = c.Transaction(func(tx *Session) (interface{}, error) {
+				return nil, fn(tx, batch)
+			})
+		} else {
+			err = fn(c, batch)
+		}
+		if err != nil {
+			return batch - 1, err
+		}
+
+		if size < batchSize {
+			return batch, nil
+		}
+	}
+}
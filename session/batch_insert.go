@@ -0,0 +1,62 @@
+package session
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// InsertBatch 批量插入切片中的记录，values 是结构体切片或结构体指针切片
+// 每一批最多 batchSize 条记录，同时保证一条语句的参数个数不超过 dialect 的上限，batchSize <= 0 时只按照参数上限分批
+// 所有批次在同一个事务中执行，返回插入的总行数
+func (s *Session) InsertBatch(values interface{}, batchSize int) (int64, error) {
+	records, err := recordPointers(values)
+	if err != nil || len(records) == 0 {
+		return 0, err
+	}
+
+	table := s.model(records[0]).RefTable()
+	limit := s.dialect.MaxPlaceholders() / max(len(table.Fields), 1)
+	if batchSize <= 0 || batchSize > limit {
+		batchSize = limit
+	}
+
+	var affected int64
+	err = s.withTransaction(func() error {
+		for start := 0; start < len(records); start += batchSize {
+			end := min(start+batchSize, len(records))
+			n, err := s.Insert(records[start:end]...)
+			if err != nil {
+				return err
+			}
+			affected += n
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return affected, nil
+}
+
+// recordPointers 把结构体切片或结构体指针切片统一转换成结构体指针，
+// hooks 和自动填充的字段可以写回切片中的元素
+func recordPointers(values interface{}) ([]interface{}, error) {
+	slice := reflect.Indirect(reflect.ValueOf(values))
+	if slice.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("expect a slice of records, got %s", slice.Type())
+	}
+	records := make([]interface{}, 0, slice.Len())
+	for i := 0; i < slice.Len(); i++ {
+		elem := slice.Index(i)
+		if elem.Kind() != reflect.Ptr {
+			elem = elem.Addr()
+		}
+		records = append(records, elem.Interface())
+	}
+	for _, record := range records {
+		if modelType(record) != modelType(records[0]) {
+			return nil, fmt.Errorf("records must be of the same model, got %s and %s", modelType(records[0]), modelType(record))
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,26 @@
+package session
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSession_InsertBatch(t *testing.T) {
+	s := testRecordInit(t)
+	var users []User
+	for i := 0; i < 25; i++ {
+		users = append(users, User{Name: fmt.Sprintf("user%d", i), Age: i})
+	}
+	affected, err := s.InsertBatch(users, 10)
+	count, _ := s.Count()
+	if err != nil || affected != 25 || count != 27 {
+		t.Fatal("failed to insert in batches", affected, err)
+	}
+}
+
+func TestSession_InsertDifferentModels(t *testing.T) {
+	s := testRecordInit(t)
+	if _, err := s.Insert(&User{Name: "Jack"}, &Account{ID: 1}); err == nil {
+		t.Fatal("expect error when inserting different models")
+	}
+}
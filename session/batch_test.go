@@ -0,0 +1,49 @@
+package session
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSession_FindInBatches(t *testing.T) {
+	s := testRecordInit(t)
+	_, _ = s.Insert(user3)
+
+	var batch []User
+	var names []string
+	batches, err := s.Where("Age > ?", 0).FindInBatches(&batch, 2, func(tx *Session, n int) error {
+		for _, u := range batch {
+			names = append(names, u.Name)
+		}
+		return nil
+	})
+	// 按照主键 Name 排序：Jack, Sam | Tom
+	if err != nil || batches != 2 || len(names) != 3 || names[0] != "Jack" || names[2] != "Tom" {
+		t.Fatal("failed to find in batches, got", batches, names)
+	}
+
+	batches, err = s.BatchInTransaction().FindInBatches(&batch, 1, func(tx *Session, n int) error {
+		if n == 2 {
+			return errors.New("Error")
+		}
+		return nil
+	})
+	if err == nil || batches != 1 {
+		t.Fatal("expect to stop at the first error, got", batches, err)
+	}
+}
+
+func TestSession_FindInBatchesSelect(t *testing.T) {
+	s := testRecordInit(t)
+	var batch []User
+	var ages []int
+	_, err := s.Select("Name").FindInBatches(&batch, 1, func(tx *Session, n int) error {
+		for _, u := range batch {
+			ages = append(ages, u.Age)
+		}
+		return nil
+	})
+	if err != nil || len(ages) != 2 || ages[0] != 0 || ages[1] != 0 {
+		t.Fatal("expect only selected columns loaded, got", ages, err)
+	}
+}
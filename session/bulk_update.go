@@ -0,0 +1,198 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"go-orm/clause"
+	"go-orm/dialect"
+	"reflect"
+)
+
+// BulkUpdate 按照主键把每条记录 columns 字段的值更新到数据库，一条语句可以把多条记录更新成不同的值
+// UPDATE $table SET $col = CASE $pk WHEN ? THEN ? ... END WHERE $pk IN (...)
+// records 是结构体切片或结构体指针切片，按照参数上限分批，所有批次在同一个事务中执行
+// 自动更新时间字段会被一起更新，版本字段不做乐观锁检查，但在数据库中加一，之前加载的对象 Save 时会得到 ErrStaleObject
+func (s *Session) BulkUpdate(records interface{}, columns ...string) (int64, error) {
+	return s.bulkUpdate(records, true, columns)
+}
+
+// bulkUpdate 是 BulkUpdate 的实现，touch 为 false 时不更新自动更新时间字段和版本字段，
+// 用于重新加密这类不改变记录内容的更新
+func (s *Session) bulkUpdate(records interface{}, touch bool, columns []string) (int64, error) {
+	values, err := recordPointers(records)
+	if err != nil || len(values) == 0 {
+		s.Clear()
+		return 0, err
+	}
+	table := s.model(values[0]).RefTable()
+	pk := table.PrimaryField
+	if pk == nil {
+		s.Clear()
+		return 0, errors.New("primary key not found")
+	}
+	columns, err = s.bulkColumns(columns, touch)
+	if err != nil {
+		s.Clear()
+		return 0, err
+	}
+
+	// 每一批都会清空语句状态，提前保存调用方设置的条件
+	base, unscoped := s.clause.Clone(), s.unscoped
+	s.Clear()
+
+	for _, value := range values {
+		s.CallMethod(BeforeUpdate, value)
+	}
+	if touch {
+		s.setBulkUpdateTime(values)
+	}
+
+	// 每条记录需要 2 * len(columns) 个 CASE 参数和 1 个 IN 参数，调用方条件的参数每一批都会占用
+	_, baseVars := base.Build(clause.WHERE)
+	batchSize := max((s.dialect.MaxPlaceholders()-len(baseVars))/(2*len(columns)+1), 1)
+	var affected int64
+	err = s.withTransaction(func() error {
+		for start := 0; start < len(values); start += batchSize {
+			batch := values[start:min(start+batchSize, len(values))]
+			rows := make([]interface{}, 0, len(batch)+4)
+			rows = append(rows, s.quote(table.Name), s.quote(pk.Name), s.quoteAll(columns))
+			keys := make([]interface{}, 0, len(batch))
+			for _, value := range batch {
+				dest := reflect.Indirect(reflect.ValueOf(value))
+				row := []interface{}{dest.FieldByName(pk.Name).Interface()}
+				for _, column := range columns {
+					row = append(row, table.GetField(column).ValueOf(dest.FieldByName(column)))
+				}
+				rows = append(rows, row)
+				keys = append(keys, row[0])
+			}
+
+			s.clause = base.Clone()
+			s.unscoped = unscoped
+			s.clause.Set(clause.BULKUPDATE, rows...)
+			s.Where(fmt.Sprintf("%s IN (%s)", s.quote(pk.Name), placeholders(len(keys))), keys...)
+			s.scopeSoftDelete()
+			sql, vars := s.clause.Build(clause.BULKUPDATE)
+			if inc := s.versionIncrement(columns); touch && inc != "" {
+				sql += ", " + inc
+			}
+			where, whereVars := s.clause.Build(clause.WHERE)
+			sql, vars = sql+" "+where, append(vars, whereVars...)
+			result, err := s.Raw(sql, vars...).Exec()
+			if err != nil {
+				return err
+			}
+			n, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			affected += n
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, value := range values {
+		s.CallMethod(AfterUpdate, value)
+	}
+	return affected, nil
+}
+
+// BulkUpsert 使用 dialect 提供的 INSERT ... ON DUPLICATE KEY UPDATE 语法批量写入记录
+// 主键已经存在的记录只更新 columns 字段，不存在的记录会被插入，因此 records 需要包含完整的字段
+// 与 BulkUpdate 相比每条记录只需要一组参数，适合整行同步数据，dialect 不支持时返回错误
+// 自动更新时间字段写入当前时间，已经存在的记录版本字段加一
+func (s *Session) BulkUpsert(records interface{}, columns ...string) (int64, error) {
+	upserter, ok := s.dialect.(dialect.Upserter)
+	if !ok {
+		s.Clear()
+		return 0, errors.New("dialect does not support upsert")
+	}
+	values, err := recordPointers(records)
+	if err != nil || len(values) == 0 {
+		s.Clear()
+		return 0, err
+	}
+	table := s.model(values[0]).RefTable()
+	if columns, err = s.bulkColumns(columns, true); err != nil {
+		s.Clear()
+		return 0, err
+	}
+
+	s.setBulkUpdateTime(values)
+	upsert := upserter.UpsertSQL(s.quoteAll(columns))
+	if inc := s.versionIncrement(columns); inc != "" {
+		upsert += ", " + inc
+	}
+
+	// 每条记录需要 len(table.Fields) 个参数，之前通过 Raw 写入的参数会和第一批一起执行
+	batchSize := max((s.dialect.MaxPlaceholders()-len(s.sqlValues))/max(len(table.Fields), 1), 1)
+	var affected int64
+	err = s.withTransaction(func() error {
+		for start := 0; start < len(values); start += batchSize {
+			s.upsert = upsert
+			n, err := s.insert(values[start:min(start+batchSize, len(values))]...)
+			if err != nil {
+				return err
+			}
+			affected += n
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return affected, nil
+}
+
+// bulkColumns 把字段名映射为列名，touch 为 true 时补充自动更新时间字段
+func (s *Session) bulkColumns(columns []string, touch bool) ([]string, error) {
+	table := s.RefTable()
+	if len(columns) == 0 {
+		return nil, errors.New("no columns to update")
+	}
+	result := make([]string, 0, len(columns))
+	for _, column := range columns {
+		field := table.GetField(column)
+		if field == nil {
+			return nil, fmt.Errorf("column %s not found in %s", column, table.Name)
+		}
+		if field == table.PrimaryField {
+			return nil, fmt.Errorf("can not update primary key %s", column)
+		}
+		result = append(result, field.Name)
+	}
+	for _, field := range table.Fields {
+		if touch && field.AutoUpdateTime && !contains(result, field.Name) && !contains(s.omits, field.Name) {
+			result = append(result, field.Name)
+		}
+	}
+	return result, nil
+}
+
+// setBulkUpdateTime 为所有记录的自动更新时间字段写入同一个当前时间
+func (s *Session) setBulkUpdateTime(values []interface{}) {
+	now := s.now()
+	for _, field := range s.RefTable().Fields {
+		if !field.AutoUpdateTime {
+			continue
+		}
+		v := reflect.ValueOf(field.TimeValue(now))
+		for _, value := range values {
+			if fv := reflect.Indirect(reflect.ValueOf(value)).FieldByName(field.Name); fv.CanSet() {
+				fv.Set(v.Convert(fv.Type()))
+			}
+		}
+	}
+}
+
+// versionIncrement 返回把版本字段加一的赋值语句，模型没有版本字段或者 columns 中已经包含版本字段时返回空字符串
+func (s *Session) versionIncrement(columns []string) string {
+	vf := s.RefTable().VersionField
+	if vf == nil || contains(columns, vf.Name) {
+		return ""
+	}
+	return fmt.Sprintf("%s = %s + 1", s.quote(vf.Name), s.quote(vf.Name))
+}
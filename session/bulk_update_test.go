@@ -0,0 +1,96 @@
+package session
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSession_BulkUpdate(t *testing.T) {
+	s := testRecordInit(t)
+	affected, err := s.BulkUpdate([]User{{"Tom", 30}, {"Sam", 40}}, "Age")
+	if err != nil || affected != 2 {
+		t.Fatal("failed to bulk update", affected, err)
+	}
+	var users []User
+	_ = s.OrderBy("Age").Find(&users)
+	if len(users) != 2 || users[0].Age != 30 || users[1].Age != 40 {
+		t.Fatal("failed to bulk update ages", users)
+	}
+
+	if _, err := s.BulkUpdate([]User{{"Tom", 30}}, "Name"); err == nil {
+		t.Fatal("expect error when updating primary key")
+	}
+	if _, err := s.BulkUpdate([]User{{"Tom", 30}}, "Unknown"); err == nil {
+		t.Fatal("expect error when updating unknown column")
+	}
+}
+
+func TestSession_BulkUpsert(t *testing.T) {
+	s := testRecordInit(t)
+	_, err := s.BulkUpsert([]*User{{"Tom", 30}, {"Jack", 20}}, "Age")
+	count, _ := s.Count()
+	if err != nil || count != 3 {
+		t.Fatal("failed to bulk upsert", err)
+	}
+	u := &User{}
+	_ = s.Where("Name = ?", "Tom").First(u)
+	if u.Age != 30 {
+		t.Fatal("failed to update existing record", u)
+	}
+}
+
+func TestSession_BulkUpdateVersion(t *testing.T) {
+	s := NewTestSession().Model(&Document{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	_, _ = s.Insert(&Document{ID: 1, Title: "draft"})
+
+	loaded := &Document{}
+	_ = s.Where("ID = ?", 1).First(loaded)
+	if _, err := s.BulkUpdate([]Document{{ID: 1, Title: "bulk"}}, "Title"); err != nil {
+		t.Fatal(err)
+	}
+	doc := &Document{}
+	_ = s.Where("ID = ?", 1).First(doc)
+	if doc.Title != "bulk" || doc.Version != 2 {
+		t.Fatal("failed to increase version on bulk update", doc)
+	}
+	loaded.Title = "conflict"
+	if _, err := s.Save(loaded); !errors.Is(err, ErrStaleObject) {
+		t.Fatal("expect ErrStaleObject, but got", err)
+	}
+}
+
+func TestSession_BulkUpsertUpdateTime(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewSession(TestDB, TestDial, WithNowFunc(func() time.Time { return now })).Model(&Post{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	_, _ = s.Insert(&Post{Title: "hello"})
+
+	now = now.Add(time.Hour)
+	post := &Post{Title: "hello", CreatedAt: now, UpdatedAt: 1}
+	if _, err := s.BulkUpsert([]*Post{post}, "CreatedAt"); err != nil {
+		t.Fatal(err)
+	}
+	loaded := &Post{}
+	_ = s.Where("Title = ?", "hello").First(loaded)
+	if post.UpdatedAt != now.UnixMilli() || loaded.UpdatedAt != now.UnixMilli() {
+		t.Fatal("failed to set update time on bulk upsert", post.UpdatedAt, loaded.UpdatedAt)
+	}
+}
+
+func TestSession_BulkUpdateWhereInChunks(t *testing.T) {
+	s := testRecordInit(t)
+	c := NewSession(TestDB, smallDialect{TestDial}).Model(&User{})
+	affected, err := c.Where("Age > ?", 20).BulkUpdate([]User{{"Tom", 30}, {"Sam", 40}}, "Age")
+	if err != nil || affected != 1 {
+		t.Fatal("failed to bulk update with where in chunks", affected, err)
+	}
+	var users []User
+	_ = s.OrderBy("Name").Find(&users)
+	if len(users) != 2 || users[0].Age != 40 || users[1].Age != 18 {
+		t.Fatal("failed to keep where in every chunk", users)
+	}
+}
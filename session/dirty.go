@@ -0,0 +1,188 @@
+package session
+
+import (
+	"errors"
+	"go-orm/schema"
+	"reflect"
+)
+
+// Change 记录一个字段在加载之后发生的变化
+type Change struct {
+	Field string
+	Old   interface{}
+	New   interface{}
+}
+
+// snapshot 记录对象当前的字段值，没有主键或主键类型不可比较的对象不会被记录
+func (s *Session) snapshot(value interface{}) {
+	table := s.RefTable()
+	key, ok := s.snapshotKey(value)
+	if !ok {
+		return
+	}
+	if s.snapshots == nil {
+		s.snapshots = make(map[string]map[interface{}][]interface{})
+	}
+	if s.snapshots[table.Name] == nil {
+		s.snapshots[table.Name] = make(map[interface{}][]interface{})
+	}
+	values := fieldValues(table, value)
+	for i, v := range values {
+		if v != nil {
+			values[i] = cloneValue(reflect.ValueOf(v)).Interface()
+		}
+	}
+	s.snapshots[table.Name][key] = values
+}
+
+// cloneValue 深拷贝 v 中的切片、map 和指针，快照不与对象共享底层数据，原地修改也能被 Changes 发现
+// 结构体中未导出的字段无法通过反射赋值，只做浅拷贝
+func cloneValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(cloneValue(v.Index(i)))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(cloneValue(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), cloneValue(iter.Value()))
+		}
+		return c
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(cloneValue(v.Elem()))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(cloneValue(v.Elem()))
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < c.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(cloneValue(v.Field(i)))
+			}
+		}
+		return c
+	}
+	return v
+}
+
+// fieldValues 返回对象上与 table 字段一一对应的原始值
+// 与 RecordValues 不同，加密和序列化的字段不会被包装，写入时由 update 统一转换
+func fieldValues(table *schema.Schema, value interface{}) []interface{} {
+	dest := reflect.Indirect(reflect.ValueOf(value))
+	values := make([]interface{}, 0, len(table.Fields))
+	for _, field := range table.Fields {
+		values = append(values, dest.FieldByName(field.Name).Interface())
+	}
+	return values
+}
+
+func (s *Session) snapshotKey(value interface{}) (interface{}, bool) {
+	pk := s.RefTable().PrimaryField
+	if pk == nil {
+		return nil, false
+	}
+	pv := reflect.Indirect(reflect.ValueOf(value)).FieldByName(pk.Name)
+	if !pv.Type().Comparable() || pv.IsZero() {
+		return nil, false
+	}
+	return pv.Interface(), true
+}
+
+// Changes 返回通过 Find/First 加载的对象从加载到现在发生变化的字段
+// 对象没有被加载过时返回 nil
+func (s *Session) Changes(value interface{}) []Change {
+	table := s.model(value).RefTable()
+	key, ok := s.snapshotKey(value)
+	if !ok {
+		return nil
+	}
+	old, ok := s.snapshots[table.Name][key]
+	if !ok {
+		return nil
+	}
+
+	var changes []Change
+	for i, v := range fieldValues(table, value) {
+		if !reflect.DeepEqual(old[i], v) {
+			changes = append(changes, Change{Field: table.Fields[i].Name, Old: old[i], New: v})
+		}
+	}
+	return changes
+}
+
+// Save 根据主键更新对象
+// 对象是通过 Find/First 加载的，只更新发生变化的字段，没有变化时不执行任何语句
+// 否则更新除主键外的所有字段
+// 模型上有关联字段时，关联记录会在同一个事务中一起保存
+func (s *Session) Save(value interface{}) (int64, error) {
+	if len(s.model(value).RefTable().Relationships) > 0 {
+		return s.saveWithAssociations(value)
+	}
+	return s.save(value)
+}
+
+func (s *Session) save(value interface{}) (int64, error) {
+	table := s.model(value).RefTable()
+	pk := table.PrimaryField
+	if pk == nil {
+		s.Clear()
+		return 0, errors.New("primary key not found")
+	}
+	key, ok := s.snapshotKey(value)
+	if !ok {
+		s.Clear()
+		return 0, errors.New("primary key is zero")
+	}
+	s.CallMethod(BeforeUpdate, value)
+
+	m := make(map[string]interface{})
+	if _, tracked := s.snapshots[table.Name][key]; tracked {
+		for _, change := range s.Changes(value) {
+			m[change.Field] = change.New
+		}
+		if len(m) == 0 {
+			s.Clear()
+			return 0, nil
+		}
+	} else {
+		values := fieldValues(table, value)
+		for i, field := range table.Fields {
+			if field != pk {
+				m[field.Name] = values[i]
+			}
+		}
+	}
+
+	affected, err := s.update(m, value, value)
+	if err != nil {
+		return 0, err
+	}
+	s.snapshot(value)
+	return affected, nil
+}
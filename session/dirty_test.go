@@ -0,0 +1,53 @@
+package session
+
+import "testing"
+
+func TestSession_Save(t *testing.T) {
+	s := testRecordInit(t)
+	u := &User{}
+	_ = s.Where("Name = ?", "Tom").First(u)
+
+	if affected, err := s.Save(u); err != nil || affected != 0 {
+		t.Fatal("expect no update for unchanged object")
+	}
+
+	u.Age = 40
+	changes := s.Changes(u)
+	if len(changes) != 1 || changes[0].Field != "Age" || changes[0].Old != 18 || changes[0].New != 40 {
+		t.Fatal("failed to detect changes, got", changes)
+	}
+	if affected, err := s.Save(u); err != nil || affected != 1 {
+		t.Fatal("failed to save changes")
+	}
+	if len(s.Changes(u)) != 0 {
+		t.Fatal("expect snapshot refreshed after save")
+	}
+}
+
+type Attachment struct {
+	ID   int `go-orm:"PRIMARY KEY"`
+	Blob []byte
+}
+
+func TestSession_SaveInPlace(t *testing.T) {
+	s := NewTestSession().Model(&Attachment{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	if _, err := s.Insert(&Attachment{ID: 1, Blob: []byte("abc")}); err != nil {
+		t.Fatal(err)
+	}
+
+	a := &Attachment{}
+	_ = s.Where("ID = ?", 1).First(a)
+	a.Blob[0] = 'z'
+	if changes := s.Changes(a); len(changes) != 1 || changes[0].Field != "Blob" {
+		t.Fatal("failed to detect in-place change, got", changes)
+	}
+	if affected, err := s.Save(a); err != nil || affected != 1 {
+		t.Fatal("failed to save in-place change", err)
+	}
+	loaded := &Attachment{}
+	if err := s.Where("ID = ?", 1).First(loaded); err != nil || string(loaded.Blob) != "zbc" {
+		t.Fatal("failed to load saved blob", err, string(loaded.Blob))
+	}
+}
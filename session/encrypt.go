@@ -0,0 +1,37 @@
+package session
+
+import "fmt"
+
+// WhereEncrypted 追加 `go-orm:"encrypt:deterministic"` 字段等于 value 的条件
+// value 在每个密钥下的密文都会作为候选值，密钥轮换后尚未重新加密的记录也能被查到
+// 字段不存在或者不是确定性加密时，错误由之后执行的 Find/First 等方法返回
+func (s *Session) WhereEncrypted(field string, value interface{}) *Session {
+	f := s.RefTable().GetField(field)
+	if f == nil {
+		return s.setErr(fmt.Errorf("field %s not found in %s", field, s.RefTable().Name))
+	}
+	ciphertexts, err := f.Ciphertexts(value)
+	if err != nil {
+		return s.setErr(err)
+	}
+	return s.Where(fmt.Sprintf("%s IN (%s)", s.quote(f.Name), placeholders(len(ciphertexts))), ciphertexts...)
+}
+
+// Reencrypt 使用当前密钥重新加密 records 的所有加密字段，用于密钥轮换
+// records 是通过 Find 加载出来的结构体切片或结构体指针切片，在同一个事务中按主键批量更新
+// 自动更新时间字段和版本字段保持不变
+func (s *Session) Reencrypt(records interface{}) (int64, error) {
+	values, err := recordPointers(records)
+	if err != nil || len(values) == 0 {
+		s.Clear()
+		return 0, err
+	}
+	var columns []string
+	for _, field := range s.model(values[0]).RefTable().Fields {
+		if field.Encryptor != nil {
+			columns = append(columns, field.Name)
+		}
+	}
+	// 重新加密不改变字段的值，不更新 UpdatedAt 和版本字段
+	return s.bulkUpdate(values, false, columns)
+}
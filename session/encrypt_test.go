@@ -0,0 +1,99 @@
+package session
+
+import (
+	"bytes"
+	"go-orm/schema"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Credential struct {
+	ID    int    `go-orm:"PRIMARY KEY"`
+	Email string `go-orm:"encrypt:deterministic"`
+	Token string `go-orm:"encrypt"`
+}
+
+func TestSession_Encrypt(t *testing.T) {
+	keys := &schema.Keyring{Current: "k1", Keys: map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}}
+	s := NewSession(TestDB, TestDial, WithKeyProvider(keys)).Model(&Credential{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	if _, err := s.Insert(&Credential{ID: 1, Email: "tom@example.com", Token: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var raw string
+	_ = s.Raw("SELECT Token FROM Credential WHERE ID = 1").QueryRow().Scan(&raw)
+	if raw == "" || strings.Contains(raw, "secret") {
+		t.Fatal("expect token encrypted at rest", raw)
+	}
+	c := &Credential{}
+	if err := s.WhereEncrypted("Email", "tom@example.com").First(c); err != nil || c.Token != "secret" {
+		t.Fatal("failed to query encrypted field", c, err)
+	}
+
+	if err := s.WhereEncrypted("Token", "secret").First(&Credential{}); err == nil {
+		t.Fatal("expect error for non-deterministic field")
+	}
+	if err := s.WhereEncrypted("Phone", "123").First(&Credential{}); err == nil {
+		t.Fatal("expect error for unknown field")
+	}
+
+	c.Token = "changed"
+	if affected, err := s.Save(c); err != nil || affected != 1 {
+		t.Fatal("failed to save encrypted field", err)
+	}
+	if _, err := NewSession(TestDB, TestDial, WithKeyProvider(keys)).Save(&Credential{ID: 1, Email: "tom@example.com", Token: "untracked"}); err != nil {
+		t.Fatal("failed to save untracked encrypted object", err)
+	}
+	c = &Credential{}
+	if err := s.WhereEncrypted("Email", "tom@example.com").First(c); err != nil || c.Token != "untracked" {
+		t.Fatal("failed to load saved encrypted field", c, err)
+	}
+
+	// 轮换密钥后旧数据仍然可以查询，重新加密后使用新密钥
+	keys.Keys["k2"], keys.Current = bytes.Repeat([]byte{2}, 32), "k2"
+	var credentials []Credential
+	if err := s.WhereEncrypted("Email", "tom@example.com").Find(&credentials); err != nil || len(credentials) != 1 {
+		t.Fatal("failed to query after key rotation", err)
+	}
+	if _, err := s.Reencrypt(credentials); err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Raw("SELECT Token FROM Credential WHERE ID = 1").QueryRow().Scan(&raw)
+	if !strings.HasPrefix(raw, "k2:") {
+		t.Fatal("failed to reencrypt with current key", raw)
+	}
+}
+
+type Vault struct {
+	ID        int    `go-orm:"PRIMARY KEY"`
+	Token     string `go-orm:"encrypt"`
+	Version   int    `go-orm:"version"`
+	UpdatedAt time.Time
+}
+
+// 重新加密不改变记录的内容，不更新 UpdatedAt 和版本字段
+func TestSession_ReencryptKeepsVersion(t *testing.T) {
+	keys := &schema.Keyring{Current: "k1", Keys: map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}}
+	updatedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewSession(TestDB, TestDial, WithKeyProvider(keys), WithNowFunc(func() time.Time { return updatedAt })).Model(&Vault{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	if _, err := s.Insert(&Vault{ID: 1, Token: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	keys.Keys["k2"], keys.Current = bytes.Repeat([]byte{2}, 32), "k2"
+	updatedAt = updatedAt.Add(time.Hour)
+	var vaults []Vault
+	_ = s.Find(&vaults)
+	if _, err := s.Reencrypt(vaults); err != nil {
+		t.Fatal(err)
+	}
+	v := &Vault{}
+	if err := s.Where("ID = ?", 1).First(v); err != nil || v.Token != "secret" || v.Version != 1 || !v.UpdatedAt.Equal(updatedAt.Add(-time.Hour)) {
+		t.Fatal("expect version and update time unchanged after reencrypt", v, err)
+	}
+}
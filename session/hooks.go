@@ -19,7 +19,7 @@ const (
 // CallMethod 如果传入参数 value，则调用values上的hook方法
 // 否则调用 s.RefTable()， 即 model上的hook方法
 func (s *Session) CallMethod(method string, value interface{}) {
-	fm := reflect.ValueOf(s.RefTable()).MethodByName(method)
+	fm := reflect.ValueOf(s.RefTable().Model).MethodByName(method)
 	if value != nil {
 		fm = reflect.ValueOf(value).MethodByName(method)
 	}
@@ -0,0 +1,64 @@
+package session
+
+import (
+	"iter"
+	"reflect"
+)
+
+// Iter 以迭代器的方式逐行读取查询结果，每一行都是一个新的 value 类型的结构体指针
+// 查询在开始迭代时才执行，每次迭代都重新执行，提前结束迭代时会关闭底层的 *sql.Rows
+// 迭代出的对象不记录快照，Save 时更新除主键外的所有字段
+//
+//	for v, err := range s.Where("Age > ?", 18).Iter(&User{}) {
+//		u := v.(*User)
+//	}
+func (s *Session) Iter(value interface{}) iter.Seq2[interface{}, error] {
+	typ := modelType(value)
+	// 调用 Iter 时保存查询条件并清空语句状态，迭代前恢复，
+	// 多次迭代或者迭代前在 Session 上构造其他语句时，查询条件不会丢失
+	st := s.saveStatement()
+	s.Clear()
+	return func(yield func(interface{}, error) bool) {
+		s.restoreStatement(st)
+		s.model(value)
+		rows, err := s.Rows()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			dest := reflect.New(typ).Interface()
+			if err := s.ScanRow(rows, dest); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(dest, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// Iter 是 Session.Iter 的泛型版本，直接返回 *T
+//
+//	for u, err := range session.Iter[User](s.Where("Age > ?", 18)) {
+//	}
+func Iter[T any](s *Session) iter.Seq2[*T, error] {
+	seq := s.Iter(new(T))
+	return func(yield func(*T, error) bool) {
+		for v, err := range seq {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(v.(*T), nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package session
+
+import "testing"
+
+func TestSession_Iter(t *testing.T) {
+	s := testRecordInit(t)
+	var names []string
+	for u, err := range Iter[User](s.OrderBy("Age ASC")) {
+		if err != nil {
+			t.Fatal("failed to iterate", err)
+		}
+		names = append(names, u.Name)
+	}
+	if len(names) != 2 || names[0] != "Tom" || names[1] != "Sam" {
+		t.Fatal("failed to iterate all records, got", names)
+	}
+	if len(s.snapshots["User"]) != 0 {
+		t.Fatal("expect no snapshots for iterated records, got", len(s.snapshots["User"]))
+	}
+
+	count := 0
+	for range s.Iter(&User{}) {
+		count++
+		break
+	}
+	if count != 1 {
+		t.Fatal("failed to stop iteration early")
+	}
+}
+
+func TestSession_ScanRow(t *testing.T) {
+	s := testRecordInit(t)
+	rows, err := s.Model(&User{}).Where("Name = ?", "Sam").Rows()
+	if err != nil {
+		t.Fatal("failed to query rows", err)
+	}
+	defer rows.Close()
+
+	u := &User{}
+	if !rows.Next() || s.ScanRow(rows, u) != nil || u.Age != 25 {
+		t.Fatal("failed to scan row, got", u)
+	}
+}
+
+// 同一个迭代器可以多次迭代，迭代前在 Session 上执行其他语句也不会丢失查询条件
+func TestSession_IterTwice(t *testing.T) {
+	s := testRecordInit(t)
+	seq := Iter[User](s.Where("Name = ?", "Tom"))
+	if count, _ := s.Count(); count != 2 {
+		t.Fatal("expect count without the iterator's condition, but got", count)
+	}
+	for i := 0; i < 2; i++ {
+		var names []string
+		for u, err := range seq {
+			if err != nil {
+				t.Fatal("failed to iterate", err)
+			}
+			names = append(names, u.Name)
+		}
+		if len(names) != 1 || names[0] != "Tom" {
+			t.Fatalf("iteration %d: expect [Tom], but got %v", i+1, names)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-orm/dialect"
+)
+
+// JSONExtract 追加条件：JSON 列 column 中 path 路径上的值等于 value
+// Dialect 不支持 JSON 查询时，错误由之后执行的 Find/First 等方法返回
+// s.JSONExtract("Settings", "$.theme", "dark").Find(&users)
+func (s *Session) JSONExtract(column, path string, value interface{}) *Session {
+	j, err := s.jsonDialect()
+	if err != nil {
+		return s.setErr(err)
+	}
+	return s.Where(j.JSONExtractSQL(s.quote(column))+" = ?", path, value)
+}
+
+// JSONContains 追加条件：JSON 列 column 包含 value 编码后的 JSON 文档
+// value 无法编码或者 Dialect 不支持 JSON 查询时，错误由之后执行的 Find/First 等方法返回
+// s.JSONContains("Tags", []string{"go"}).Find(&posts)
+func (s *Session) JSONContains(column string, value interface{}) *Session {
+	j, err := s.jsonDialect()
+	if err != nil {
+		return s.setErr(err)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return s.setErr(err)
+	}
+	return s.Where(j.JSONContainsSQL(s.quote(column)), string(data))
+}
+
+// jsonDialect 返回生成 JSON 条件的 Dialect，没有实现 JSONer 时返回错误
+func (s *Session) jsonDialect() (dialect.JSONer, error) {
+	if j, ok := s.dialect.(dialect.JSONer); ok {
+		return j, nil
+	}
+	return nil, fmt.Errorf("dialect %T does not support JSON queries", s.dialect)
+}
@@ -0,0 +1,81 @@
+package session
+
+import "testing"
+
+type Options struct {
+	Theme string
+}
+
+type Profile struct {
+	ID      int               `go-orm:"PRIMARY KEY"`
+	Labels  map[string]string `go-orm:"serializer:json"`
+	Options *Options          `go-orm:"serializer:json"`
+	Tags    []string          `go-orm:"serializer:json"`
+}
+
+func TestSession_Serializer(t *testing.T) {
+	s := NewTestSession().Model(&Profile{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	_, err := s.Insert(
+		&Profile{ID: 1, Labels: map[string]string{"env": "prod"}, Options: &Options{Theme: "dark"}, Tags: []string{"go", "orm"}},
+		&Profile{ID: 2, Tags: []string{"db"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var profiles []Profile
+	if err := s.OrderBy("ID").Find(&profiles); err != nil || len(profiles) != 2 {
+		t.Fatal("failed to find serialized records", err)
+	}
+	if profiles[0].Labels["env"] != "prod" || profiles[0].Options.Theme != "dark" || len(profiles[0].Tags) != 2 {
+		t.Fatal("failed to decode serialized fields", profiles[0])
+	}
+	if profiles[1].Options != nil {
+		t.Fatal("expect nil options", profiles[1].Options)
+	}
+
+	p := &Profile{}
+	if err := s.JSONExtract("Options", "$.Theme", "dark").First(p); err != nil || p.ID != 1 {
+		t.Fatal("failed to query by json path", err)
+	}
+	var tagged []Profile
+	if err := s.JSONContains("Tags", []string{"db"}).Find(&tagged); err != nil || len(tagged) != 1 || tagged[0].ID != 2 {
+		t.Fatal("failed to query by json contains", err)
+	}
+
+	if err := s.JSONContains("Tags", make(chan int)).Find(&tagged); err == nil {
+		t.Fatal("expect error for value that can not be encoded")
+	}
+	// smallDialect 没有实现 dialect.JSONer
+	c := NewSession(TestDB, smallDialect{TestDial}).Model(&Profile{})
+	if err := c.JSONExtract("Options", "$.Theme", "dark").First(p); err == nil {
+		t.Fatal("expect error for dialect without JSON support")
+	}
+}
+
+func TestSession_SaveSerializer(t *testing.T) {
+	s := NewTestSession().Model(&Profile{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	if _, err := s.Insert(&Profile{ID: 1, Labels: map[string]string{"env": "prod"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Profile{}
+	_ = s.Where("ID = ?", 1).First(p)
+	p.Labels = map[string]string{"env": "test"}
+	if affected, err := s.Save(p); err != nil || affected != 1 {
+		t.Fatal("failed to save serialized field", err)
+	}
+	// 没有加载过的对象更新所有字段
+	if _, err := NewTestSession().Save(&Profile{ID: 1, Labels: map[string]string{"env": "dev"}, Tags: []string{"go"}}); err != nil {
+		t.Fatal("failed to save untracked object", err)
+	}
+
+	loaded := &Profile{}
+	if err := s.Where("ID = ?", 1).First(loaded); err != nil || loaded.Labels["env"] != "dev" || len(loaded.Tags) != 1 {
+		t.Fatal("failed to load saved serialized fields", err, loaded)
+	}
+}
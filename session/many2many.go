@@ -0,0 +1,209 @@
+package session
+
+import (
+	"fmt"
+	"go-orm/schema"
+	"reflect"
+	"strings"
+)
+
+// Association 用于操作模型上的多对多关联，连接表的读写同样经过 clause 生成器和 hooks
+// s.Association(&user, "Roles").Append(&role)
+type Association struct {
+	session *Session
+	owner   interface{}
+	rel     *schema.Relationship
+	Error   error
+}
+
+// Association 返回 owner 上名为 name 的多对多关联，关联不存在时 Error 不为 nil
+func (s *Session) Association(owner interface{}, name string) *Association {
+	a := &Association{session: s, owner: owner}
+	a.rel = s.model(owner).RefTable().GetRelationship(name)
+	if a.rel == nil || a.rel.Type != schema.Many2Many {
+		a.Error = fmt.Errorf("many2many association %s not found", name)
+	}
+	return a
+}
+
+// Append 保存新的关联记录并在连接表中添加关联，同时追加到 owner 的关联字段上
+func (a *Association) Append(values ...interface{}) error {
+	if a.Error != nil {
+		return a.Error
+	}
+	err := a.session.withTransaction(func() error {
+		return a.link(values)
+	})
+	if err == nil {
+		a.appendField(values)
+	}
+	return err
+}
+
+// Replace 用 values 替换 owner 当前所有的关联
+func (a *Association) Replace(values ...interface{}) error {
+	if a.Error != nil {
+		return a.Error
+	}
+	err := a.session.withTransaction(func() error {
+		if _, err := a.joinSession().HardDelete(); err != nil {
+			return err
+		}
+		return a.link(values)
+	})
+	if fv := a.field(); err == nil && fv.CanSet() {
+		fv.Set(reflect.MakeSlice(fv.Type(), 0, len(values)))
+		a.appendField(values)
+	}
+	return err
+}
+
+// Delete 删除 owner 与 values 之间的关联，关联记录本身不会被删除
+func (a *Association) Delete(values ...interface{}) error {
+	if a.Error != nil {
+		return a.Error
+	}
+	keys := a.keys(values)
+	if len(keys) == 0 {
+		return nil
+	}
+	if _, err := a.joinSession(keys...).HardDelete(); err != nil {
+		return err
+	}
+	a.removeField(keys)
+	return nil
+}
+
+// Clear 删除 owner 的所有关联，关联记录本身不会被删除
+func (a *Association) Clear() error {
+	if a.Error != nil {
+		return a.Error
+	}
+	if _, err := a.joinSession().HardDelete(); err != nil {
+		return err
+	}
+	if fv := a.field(); fv.CanSet() {
+		fv.Set(reflect.Zero(fv.Type()))
+	}
+	return nil
+}
+
+// Count 返回连接表中 owner 的关联数量
+func (a *Association) Count() (int64, error) {
+	if a.Error != nil {
+		return 0, a.Error
+	}
+	return a.joinSession().Count()
+}
+
+// link 插入主键为零值的关联记录，然后在连接表中写入关联，已经存在的关联会先被删除以避免重复
+// owner 的键为零值时返回错误，避免在连接表中写入没有 owner 的关联
+func (a *Association) link(values []interface{}) error {
+	if len(values) > 0 && reflect.ValueOf(a.ownerKey()).IsZero() {
+		return fmt.Errorf("can not link %s: owner %s is zero", a.rel.Name, a.rel.ForeignKey)
+	}
+	for _, value := range values {
+		c := a.session.clone()
+		if c.model(value).isNewRecord(value) {
+			if _, err := c.Insert(value); err != nil {
+				return err
+			}
+		}
+	}
+	keys := a.keys(values)
+	if len(keys) == 0 {
+		return nil
+	}
+	if _, err := a.joinSession(keys...).HardDelete(); err != nil {
+		return err
+	}
+
+	joinType := reflect.TypeOf(a.rel.JoinTable.Model).Elem()
+	rows := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		row := reflect.New(joinType)
+		setField(row.Elem().FieldByName(a.rel.JoinForeignKey), reflect.ValueOf(a.ownerKey()))
+		setField(row.Elem().FieldByName(a.rel.JoinReferences), reflect.ValueOf(key))
+		rows = append(rows, row.Interface())
+	}
+	_, err := a.joinTable().Insert(rows...)
+	return err
+}
+
+// joinTable 返回一个以连接表为 Model 的 Session，与当前 Session 共享事务
+func (a *Association) joinTable() *Session {
+	s := a.session.clone()
+	s.refTable = a.rel.JoinTable
+	return s
+}
+
+// joinSession 返回追加了 owner 条件的连接表 Session，keys 不为空时只匹配这些关联记录
+func (a *Association) joinSession(keys ...interface{}) *Session {
+	s := a.joinTable()
+	s.Where(s.quote(a.rel.JoinForeignKey)+" = ?", a.ownerKey())
+	if len(keys) > 0 {
+		s.Where(fmt.Sprintf("%s IN (%s)", s.quote(a.rel.JoinReferences), placeholders(len(keys))), keys...)
+	}
+	return s
+}
+
+func (a *Association) ownerKey() interface{} {
+	return reflect.Indirect(reflect.ValueOf(a.owner)).FieldByName(a.rel.ForeignKey).Interface()
+}
+
+// keys 返回关联记录上被连接表引用的键
+func (a *Association) keys(values []interface{}) []interface{} {
+	keys := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		keys = append(keys, reflect.Indirect(reflect.ValueOf(value)).FieldByName(a.rel.References).Interface())
+	}
+	return keys
+}
+
+func (a *Association) field() reflect.Value {
+	return reflect.Indirect(reflect.ValueOf(a.owner)).FieldByName(a.rel.Name)
+}
+
+// appendField 把关联记录追加到 owner 的关联字段上，字段元素可以是结构体或结构体指针
+func (a *Association) appendField(values []interface{}) {
+	fv := a.field()
+	if !fv.CanSet() {
+		return
+	}
+	for _, value := range values {
+		v := reflect.ValueOf(value)
+		if fv.Type().Elem().Kind() != reflect.Ptr {
+			v = reflect.Indirect(v)
+		}
+		fv.Set(reflect.Append(fv, v))
+	}
+}
+
+// removeField 从 owner 的关联字段上移除键在 keys 中的关联记录
+func (a *Association) removeField(keys []interface{}) {
+	fv := a.field()
+	if !fv.CanSet() {
+		return
+	}
+	kept := reflect.MakeSlice(fv.Type(), 0, fv.Len())
+	for i := 0; i < fv.Len(); i++ {
+		key := reflect.Indirect(fv.Index(i)).FieldByName(a.rel.References).Interface()
+		if !containsValue(keys, key) {
+			kept = reflect.Append(kept, fv.Index(i))
+		}
+	}
+	fv.Set(kept)
+}
+
+func containsValue(list []interface{}, target interface{}) bool {
+	for _, v := range list {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+func placeholders(num int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", num), ", ")
+}
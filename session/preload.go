@@ -0,0 +1,212 @@
+package session
+
+import (
+	"fmt"
+	"go-orm/clause"
+	"go-orm/schema"
+	"reflect"
+	"strings"
+)
+
+type preload struct {
+	name  string
+	conds []interface{}
+}
+
+// Preload 在 Find/First 之后预加载关联字段，每一层关联只执行一次 WHERE key IN (...) 查询
+// 嵌套的关联用 . 分隔，conds 是可选的查询条件，第一个元素是条件语句，其余是参数
+// s.Preload("Orders", "Paid = ?", false).Preload("Orders.Items").Find(&users)
+func (s *Session) Preload(name string, conds ...interface{}) *Session {
+	s.preloads = append(s.preloads, preload{name: name, conds: conds})
+	return s
+}
+
+// preloadAll 为 destSlice 中的所有记录加载 preloads 声明的关联
+func (s *Session) preloadAll(destSlice reflect.Value, table *schema.Schema, preloads []preload) error {
+	// 按照第一层关联名分组，剩下的路径交给加载关联记录的 Session 继续预加载
+	var names []string
+	groups := make(map[string]*preload)
+	nested := make(map[string][]preload)
+	for _, p := range preloads {
+		name, rest, ok := strings.Cut(p.name, ".")
+		if _, exists := groups[name]; !exists {
+			names = append(names, name)
+			groups[name] = &preload{name: name}
+		}
+		if ok {
+			nested[name] = append(nested[name], preload{name: rest, conds: p.conds})
+		} else {
+			groups[name].conds = p.conds
+		}
+	}
+
+	for _, name := range names {
+		rel := table.GetRelationship(name)
+		if rel == nil {
+			return fmt.Errorf("relationship %s not found in %s", name, table.Name)
+		}
+		c := s.clone()
+		c.preloads = nested[name]
+		if conds := groups[name].conds; len(conds) > 0 {
+			c.Where(conds[0], conds[1:]...)
+		}
+		var err error
+		if rel.Type == schema.Many2Many {
+			err = c.preloadMany2Many(destSlice, rel)
+		} else {
+			err = c.preloadRelation(destSlice, rel)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// preloadRelation 加载 HasOne/HasMany/BelongsTo 关联并按照外键写回每条记录
+func (s *Session) preloadRelation(destSlice reflect.Value, rel *schema.Relationship) error {
+	// ownerKey 是当前记录上的键，relatedKey 是关联记录上与之对应的键
+	ownerKey, relatedKey := rel.References, rel.ForeignKey
+	if rel.Type == schema.BelongsTo {
+		ownerKey, relatedKey = rel.ForeignKey, rel.References
+	}
+
+	keys := collectKeys(destSlice, ownerKey)
+	if len(keys) == 0 {
+		return nil
+	}
+	related := reflect.New(reflect.SliceOf(rel.FieldType))
+	if err := s.findIn(related.Interface(), relatedKey, keys); err != nil {
+		return err
+	}
+
+	byKey := make(map[interface{}][]reflect.Value)
+	for i := 0; i < related.Elem().Len(); i++ {
+		record := related.Elem().Index(i)
+		key := normalizeKey(record.FieldByName(relatedKey))
+		byKey[key] = append(byKey[key], record)
+	}
+	for i := 0; i < destSlice.Len(); i++ {
+		dest := destSlice.Index(i)
+		setRelated(dest.FieldByName(rel.Name), byKey[normalizeKey(dest.FieldByName(ownerKey))])
+	}
+	return nil
+}
+
+// preloadMany2Many 先从连接表查出关联的键，再加载关联记录并写回每条记录
+func (s *Session) preloadMany2Many(destSlice reflect.Value, rel *schema.Relationship) error {
+	keys := collectKeys(destSlice, rel.ForeignKey)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	join := s.clone()
+	join.refTable = rel.JoinTable
+	pairs := reflect.New(reflect.SliceOf(reflect.TypeOf(rel.JoinTable.Model).Elem()))
+	if err := join.findIn(pairs.Interface(), rel.JoinForeignKey, keys); err != nil {
+		return err
+	}
+	refsByOwner := make(map[interface{}][]interface{})
+	for i := 0; i < pairs.Elem().Len(); i++ {
+		pair := pairs.Elem().Index(i)
+		owner := normalizeKey(pair.FieldByName(rel.JoinForeignKey))
+		refsByOwner[owner] = append(refsByOwner[owner], normalizeKey(pair.FieldByName(rel.JoinReferences)))
+	}
+	refs := collectKeys(pairs.Elem(), rel.JoinReferences)
+	if len(refs) == 0 {
+		return nil
+	}
+
+	related := reflect.New(reflect.SliceOf(rel.FieldType))
+	if err := s.findIn(related.Interface(), rel.References, refs); err != nil {
+		return err
+	}
+	byKey := make(map[interface{}]reflect.Value)
+	for i := 0; i < related.Elem().Len(); i++ {
+		record := related.Elem().Index(i)
+		byKey[normalizeKey(record.FieldByName(rel.References))] = record
+	}
+
+	for i := 0; i < destSlice.Len(); i++ {
+		dest := destSlice.Index(i)
+		var records []reflect.Value
+		for _, ref := range refsByOwner[normalizeKey(dest.FieldByName(rel.ForeignKey))] {
+			if record, ok := byKey[ref]; ok {
+				records = append(records, record)
+			}
+		}
+		setRelated(dest.FieldByName(rel.Name), records)
+	}
+	return nil
+}
+
+// findIn 查询 column 的值在 keys 中的记录并追加到 dest 指向的切片
+// keys 按照 Dialect 的参数个数上限分批查询，Session 上已有的条件和嵌套的预加载对每一批都生效
+func (s *Session) findIn(dest interface{}, column string, keys []interface{}) error {
+	slice := reflect.ValueOf(dest).Elem()
+	base, preloads, err := s.clause.Clone(), s.preloads, s.err
+	_, vars := base.Build(clause.WHERE)
+	size := max(s.dialect.MaxPlaceholders()-len(vars), 1)
+	for start := 0; start < len(keys); start += size {
+		chunk := keys[start:min(start+size, len(keys))]
+		s.clause, s.preloads, s.err = base.Clone(), preloads, err
+		s.Where(fmt.Sprintf("%s IN (%s)", s.quote(column), placeholders(len(chunk))), chunk...)
+		// 每一批查询到独立的切片中，嵌套的预加载只处理这一批记录
+		part := reflect.New(slice.Type())
+		if err := s.Find(part.Interface()); err != nil {
+			return err
+		}
+		slice.Set(reflect.AppendSlice(slice, part.Elem()))
+	}
+	return nil
+}
+
+// collectKeys 返回所有记录上 name 字段去重后的非零值
+func collectKeys(destSlice reflect.Value, name string) []interface{} {
+	var keys []interface{}
+	seen := make(map[interface{}]bool)
+	for i := 0; i < destSlice.Len(); i++ {
+		fv := destSlice.Index(i).FieldByName(name)
+		if fv.IsZero() || seen[normalizeKey(fv)] {
+			continue
+		}
+		seen[normalizeKey(fv)] = true
+		keys = append(keys, fv.Interface())
+	}
+	return keys
+}
+
+// normalizeKey 把不同宽度的整数统一，使 int 和 int64 类型的外键可以互相匹配
+func normalizeKey(v reflect.Value) interface{} {
+	switch {
+	case v.CanInt():
+		return v.Int()
+	case v.CanUint():
+		return v.Uint()
+	}
+	return v.Interface()
+}
+
+// setRelated 把关联记录写入关联字段，字段可以是结构体、结构体指针或者它们的切片
+func setRelated(fv reflect.Value, records []reflect.Value) {
+	typ := fv.Type()
+	if typ.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(typ, 0, len(records))
+		for _, record := range records {
+			slice = reflect.Append(slice, asKind(record, typ.Elem().Kind()))
+		}
+		fv.Set(slice)
+		return
+	}
+	if len(records) > 0 {
+		fv.Set(asKind(records[0], typ.Kind()))
+	}
+}
+
+// asKind 需要指针时返回记录的地址，否则返回记录本身
+func asKind(record reflect.Value, kind reflect.Kind) reflect.Value {
+	if kind == reflect.Ptr {
+		return record.Addr()
+	}
+	return record
+}
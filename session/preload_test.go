@@ -0,0 +1,57 @@
+package session
+
+import (
+	"go-orm/clause"
+	"go-orm/dialect"
+	"testing"
+)
+
+func TestSession_Preload(t *testing.T) {
+	s := testAssociationInit(t)
+	_, _ = s.Insert(&Player{Name: "Tom", Team: &Team{Name: "Red"}, Items: []Item{{Name: "sword"}, {Name: "shield"}}})
+	_, _ = s.Insert(&Player{Name: "Sam", Team: &Team{Name: "Blue"}, Items: []Item{{Name: "bow"}}})
+
+	var players []Player
+	if err := s.Preload("Team").Preload("Items").OrderBy("ID").Find(&players); err != nil || len(players) != 2 {
+		t.Fatal("failed to preload", err)
+	}
+	if players[0].Team == nil || players[0].Team.Name != "Red" || len(players[0].Items) != 2 {
+		t.Fatal("failed to preload associations of Tom, got", players[0])
+	}
+	if players[1].Team == nil || players[1].Team.Name != "Blue" || len(players[1].Items) != 1 {
+		t.Fatal("failed to preload associations of Sam, got", players[1])
+	}
+
+	players = nil
+	_ = s.Preload("Items", "Name = ?", "bow").OrderBy("ID").Find(&players)
+	if len(players[0].Items) != 0 || len(players[1].Items) != 1 {
+		t.Fatal("failed to preload with conditions")
+	}
+}
+
+// smallDialect 限制每条语句只能使用很少的参数，用于测试分批查询
+type smallDialect struct {
+	dialect.Dialect
+}
+
+func (smallDialect) MaxPlaceholders() int {
+	return 2
+}
+
+func TestSession_PreloadInChunks(t *testing.T) {
+	s := testAssociationInit(t)
+	for _, name := range []string{"Tom", "Sam", "Jack"} {
+		_, _ = s.Insert(&Player{Name: name, Items: []Item{{Name: name + "'s sword"}, {Name: "bow"}}})
+	}
+
+	var players []Player
+	c := NewSession(TestDB, smallDialect{TestDial})
+	if err := c.Preload("Items", clause.Expr{SQL: "Name <> ?", Vars: []interface{}{"bow"}}).OrderBy("ID").Find(&players); err != nil || len(players) != 3 {
+		t.Fatal("failed to preload in chunks", err)
+	}
+	for _, p := range players {
+		if len(p.Items) != 1 || p.Items[0].Name != p.Name+"'s sword" {
+			t.Fatal("failed to preload items of", p.Name, p.Items)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package session
+
+import (
+	"fmt"
+	"go-orm/clause"
+	"strings"
+)
+
+// quote 使用 dialect 的规则给表名、列名加上引号
+func (s *Session) quote(name string) string {
+	return s.dialect.Quote(name)
+}
+
+func (s *Session) quoteAll(names []string) []string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, s.quote(name))
+	}
+	return quoted
+}
+
+// column 校验 name 是模型上的字段，返回加上引号的列名
+func (s *Session) column(name string) (string, error) {
+	field := s.RefTable().GetField(name)
+	if field == nil {
+		return "", fmt.Errorf("column %s not found in %s", name, s.RefTable().Name)
+	}
+	return s.quote(field.Name), nil
+}
+
+// selectColumns 返回 SELECT 的列和 Expr 的参数，没有调用 Select 时查询所有字段
+func (s *Session) selectColumns() ([]string, []interface{}, error) {
+	if len(s.selects) == 0 && len(s.selectExprs) == 0 {
+		return s.quoteAll(s.RefTable().FieldNames), nil, nil
+	}
+	var columns []string
+	var vars []interface{}
+	for _, name := range s.selects {
+		column, err := s.column(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		columns = append(columns, column)
+	}
+	for _, expr := range s.selectExprs {
+		columns = append(columns, expr.SQL)
+		vars = append(vars, expr.Vars...)
+	}
+	return columns, vars, nil
+}
+
+// setOrderBy 把 OrderBy 传入的排序转换为 ORDER BY 子句
+// 字符串形如 "Age DESC, Name"，每一列都必须是模型上的字段，只能跟 ASC 或 DESC；clause.Expr 原样拼接
+func (s *Session) setOrderBy() error {
+	if len(s.orders) == 0 {
+		return nil
+	}
+	var items []string
+	var vars []interface{}
+	for _, order := range s.orders {
+		switch v := order.(type) {
+		case clause.Expr:
+			items = append(items, v.SQL)
+			vars = append(vars, v.Vars...)
+		case string:
+			for _, item := range strings.Split(v, ",") {
+				parts := strings.Fields(item)
+				if len(parts) == 0 || len(parts) > 2 {
+					return fmt.Errorf("invalid order by %q", item)
+				}
+				column, err := s.column(parts[0])
+				if err != nil {
+					return err
+				}
+				if len(parts) == 2 {
+					direction := strings.ToUpper(parts[1])
+					if direction != "ASC" && direction != "DESC" {
+						return fmt.Errorf("invalid order by %q", item)
+					}
+					column += " " + direction
+				}
+				items = append(items, column)
+			}
+		default:
+			return fmt.Errorf("invalid order by %v", order)
+		}
+	}
+	s.clause.Set(clause.ORDERBY, append([]interface{}{strings.Join(items, ", ")}, vars...)...)
+	return nil
+}
@@ -0,0 +1,47 @@
+package session
+
+import (
+	"go-orm/clause"
+	"testing"
+)
+
+type Group struct {
+	ID   int `go-orm:"PRIMARY KEY"`
+	Name string
+}
+
+func TestSession_QuoteReservedWords(t *testing.T) {
+	s := NewTestSession().Model(&Group{})
+	_ = s.DropTable()
+	if err := s.CreateTable(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Insert(&Group{ID: 1, Name: "admin"}, &Group{ID: 2, Name: "dev"}); err != nil {
+		t.Fatal(err)
+	}
+	var groups []Group
+	if err := s.Select("ID", clause.Expr{SQL: "UPPER(`Name`) AS Name"}).OrderBy("ID DESC").Find(&groups); err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 || groups[0].ID != 2 || groups[0].Name != "DEV" {
+		t.Fatal("failed to query table named by reserved word", groups)
+	}
+}
+
+func TestSession_InvalidColumns(t *testing.T) {
+	s := NewTestSession().Model(&User{})
+	for _, order := range []string{"Age; DROP TABLE User", "Unknown", "Age DESC LIMIT 1", "Name ASC,"} {
+		if _, err := s.OrderBy(order).Rows(); err == nil {
+			t.Fatalf("expect error for order by %q", order)
+		}
+	}
+	if _, err := s.Select("Unknown").Rows(); err == nil {
+		t.Fatal("expect error for unknown select column")
+	}
+	if _, err := s.Update("Age = 0, Name", "Tom"); err == nil {
+		t.Fatal("expect error for unknown update column")
+	}
+	if _, err := s.Model(&User{}).Select("Unknown").Updates(&User{Name: "Tom"}); err == nil {
+		t.Fatal("expect error for unknown updates column")
+	}
+}
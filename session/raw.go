@@ -1,12 +1,15 @@
 package session
 
 import (
+	"context"
 	"database/sql"
 	"go-orm/clause"
 	"go-orm/dialect"
 	"go-orm/log"
 	"go-orm/schema"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Session struct {
@@ -17,6 +20,43 @@ type Session struct {
 	sql       strings.Builder
 	sqlValues []interface{}
 	clause    clause.Clause
+	// target 是当前语句通过 Model 显式指定的对象，为 nil 时不按主键限定更新的范围
+	target interface{}
+	// selects 和 omits 记录 Select/Omit 指定的字段，只对当前这条语句生效
+	selects []string
+	omits   []string
+	// selectExprs 是 Select 传入的 clause.Expr，原样拼接到 SELECT 的列中
+	selectExprs []clause.Expr
+	// orders 是 OrderBy 传入的排序，查询时才根据模型校验列名
+	orders []interface{}
+	// nowFunc 是自动时间戳使用的时钟，为 nil 时使用 time.Now
+	nowFunc func() time.Time
+	// unscoped 为 true 时，当前语句不会自动过滤软删除的记录
+	unscoped bool
+	// preloads 记录 Find 之后需要预加载的关联
+	preloads []preload
+	// err 是构造语句时发生的错误，例如命名参数缺失，由 Exec/QueryRows 返回
+	err error
+	// upsert 是追加在下一条 INSERT 语句之后的冲突处理子句
+	upsert string
+	// disallowUnknown 为 true 时 Scan 遇到结构体上不存在的列返回错误
+	disallowUnknown bool
+	// batchTx 为 true 时 FindInBatches 的每一批回调在独立的事务中执行
+	batchTx bool
+	// savepoints 是嵌套事务创建的保存点，最后一个是最内层
+	savepoints []string
+	// callbacks 是每一层事务注册的 AfterCommit/AfterRollback 回调，与 savepoints 一一对应外加最外层事务
+	callbacks []*txCallbacks
+	// schemas 缓存结构体类型的解析结果，为 nil 时每次切换模型都重新解析
+	schemas *sync.Map
+	// types 是 Engine 注册的自定义类型的列类型，解析模型时优先使用
+	types *dialect.Types
+	// keys 是加密字段使用的密钥
+	keys schema.KeyProvider
+	// ctx 是执行语句使用的 context，为 nil 时使用 context.Background()
+	ctx context.Context
+	// snapshots 保存 Find 加载出来的对象的字段值，按表名和主键索引，用于 Save 时计算变化的字段
+	snapshots map[string]map[interface{}][]interface{}
 }
 
 // CommonDB is a minimal function set of db
@@ -24,22 +64,144 @@ type CommonDB interface {
 	Query(query string, args ...interface{}) (*sql.Rows, error)
 	QueryRow(query string, args ...interface{}) *sql.Row
 	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 }
 
 var _ CommonDB = (*sql.DB)(nil)
 var _ CommonDB = (*sql.Tx)(nil)
 
-func NewSession(db *sql.DB, dialect dialect.Dialect) *Session {
-	return &Session{
+// Option 用于在创建 Session 时注入 Engine 级别的配置
+type Option func(*Session)
+
+// WithNowFunc 设置自动时间戳使用的时钟，便于测试时固定时间
+func WithNowFunc(nowFunc func() time.Time) Option {
+	return func(s *Session) {
+		s.nowFunc = nowFunc
+	}
+}
+
+// WithSchemaCache 设置解析结果的缓存，同一个类型只解析一次
+// 缓存的 key 是结构体类型，value 是 *schema.Schema
+func WithSchemaCache(cache *sync.Map) Option {
+	return func(s *Session) {
+		s.schemas = cache
+	}
+}
+
+// WithTypes 设置自定义类型的列类型注册表
+func WithTypes(types *dialect.Types) Option {
+	return func(s *Session) {
+		s.types = types
+	}
+}
+
+// WithKeyProvider 设置加密字段使用的密钥
+func WithKeyProvider(keys schema.KeyProvider) Option {
+	return func(s *Session) {
+		s.keys = keys
+	}
+}
+
+func NewSession(db *sql.DB, dialect dialect.Dialect, opts ...Option) *Session {
+	s := &Session{
 		db:      db,
 		dialect: dialect,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// clone 返回一个共享连接、事务和配置的新 Session，用于在同一个事务中操作其他模型
+// 保存点和事务回调也会被继承，新 Session 中创建的保存点不会与当前 Session 重名
+func (s *Session) clone() *Session {
+	return &Session{
+		db:         s.db,
+		dialect:    s.dialect,
+		tx:         s.tx,
+		savepoints: append([]string(nil), s.savepoints...),
+		callbacks:  append([]*txCallbacks(nil), s.callbacks...),
+		nowFunc:    s.nowFunc,
+		schemas:    s.schemas,
+		types:      s.types,
+		keys:       s.keys,
+		ctx:        s.ctx,
+	}
+}
+
+// WithContext 设置之后执行的语句使用的 context
+func (s *Session) WithContext(ctx context.Context) *Session {
+	s.ctx = ctx
+	return s
+}
+
+func (s *Session) context() context.Context {
+	if s.ctx != nil {
+		return s.ctx
+	}
+	return context.Background()
 }
 
 func (s *Session) Clear() {
 	s.sql.Reset()
 	s.sqlValues = nil
 	s.clause = clause.Clause{}
+	s.target = nil
+	s.selects = nil
+	s.selectExprs = nil
+	s.orders = nil
+	s.omits = nil
+	s.unscoped = false
+	s.preloads = nil
+	s.batchTx = false
+	s.disallowUnknown = false
+	s.upsert = ""
+	s.err = nil
+}
+
+// statement 是 Session 上只对当前这条语句生效的查询条件和配置
+// Iter、FindInBatches 等需要多次执行同一条查询时，先保存再在每次执行前恢复
+type statement struct {
+	clause          clause.Clause
+	selects         []string
+	omits           []string
+	selectExprs     []clause.Expr
+	orders          []interface{}
+	unscoped        bool
+	preloads        []preload
+	disallowUnknown bool
+	err             error
+}
+
+// saveStatement 返回当前语句状态的副本，之后修改 Session 不会影响副本
+func (s *Session) saveStatement() statement {
+	return statement{
+		clause:          s.clause.Clone(),
+		selects:         append([]string(nil), s.selects...),
+		omits:           append([]string(nil), s.omits...),
+		selectExprs:     append([]clause.Expr(nil), s.selectExprs...),
+		orders:          append([]interface{}(nil), s.orders...),
+		unscoped:        s.unscoped,
+		preloads:        append([]preload(nil), s.preloads...),
+		disallowUnknown: s.disallowUnknown,
+		err:             s.err,
+	}
+}
+
+// restoreStatement 用 st 的副本替换当前语句状态，st 可以被多次恢复
+func (s *Session) restoreStatement(st statement) {
+	s.clause = st.clause.Clone()
+	s.selects = append([]string(nil), st.selects...)
+	s.omits = append([]string(nil), st.omits...)
+	s.selectExprs = append([]clause.Expr(nil), st.selectExprs...)
+	s.orders = append([]interface{}(nil), st.orders...)
+	s.unscoped = st.unscoped
+	s.preloads = append([]preload(nil), st.preloads...)
+	s.disallowUnknown = st.disallowUnknown
+	s.err = st.err
 }
 
 func (s *Session) DB() CommonDB {
@@ -49,33 +211,63 @@ func (s *Session) DB() CommonDB {
 	return s.db
 }
 
+// Raw 追加一段 SQL 和参数，参数中有 sql.Named、map 或结构体时 sql 中的 @name 会被改写为 Dialect 的占位符
+// 多次调用 Raw 时占位符接着之前的参数编号
+// s.Raw("SELECT * FROM User WHERE Name = @name OR Nickname = @name", sql.Named("name", "Tom"))
 func (s *Session) Raw(sql string, values ...interface{}) *Session {
+	offset := len(s.sqlValues)
+	sql, values, err := clause.BindNamed(sql, values, func(n int) string {
+		return s.dialect.BindVar(offset + n)
+	})
+	if err != nil {
+		s.setErr(err)
+	}
 	s.sql.WriteString(sql)
 	s.sql.WriteString(" ")
 	s.sqlValues = append(s.sqlValues, values...)
 	return s
 }
 
+// setErr 记录构造语句时发生的第一个错误，由之后执行语句的方法返回
+func (s *Session) setErr(err error) *Session {
+	if s.err == nil {
+		s.err = err
+	}
+	return s
+}
+
 func (s *Session) Exec() (result sql.Result, err error) {
 	defer s.Clear()
+	if s.err != nil {
+		log.Error(s.err)
+		return nil, s.err
+	}
 	log.Info(s.sql.String(), s.sqlValues)
-	result, err = s.DB().Exec(s.sql.String(), s.sqlValues...)
+	result, err = s.DB().ExecContext(s.context(), s.sql.String(), s.sqlValues...)
 	if err != nil {
 		log.Error(err)
 	}
 	return
 }
 
+// QueryRow 无法返回构造语句时的错误，只记录日志，需要检查错误时使用 QueryRows
 func (s *Session) QueryRow() *sql.Row {
 	defer s.Clear()
+	if s.err != nil {
+		log.Error(s.err)
+	}
 	log.Info(s.sql.String(), s.sqlValues)
-	return s.DB().QueryRow(s.sql.String(), s.sqlValues...)
+	return s.DB().QueryRowContext(s.context(), s.sql.String(), s.sqlValues...)
 }
 
 func (s *Session) QueryRows() (rows *sql.Rows, err error) {
 	defer s.Clear()
+	if s.err != nil {
+		log.Error(s.err)
+		return nil, s.err
+	}
 	log.Info(s.sql.String(), s.sqlValues)
-	rows, err = s.DB().Query(s.sql.String(), s.sqlValues...)
+	rows, err = s.DB().QueryContext(s.context(), s.sql.String(), s.sqlValues...)
 	if err != nil {
 		log.Error(err)
 	}
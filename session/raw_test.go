@@ -55,3 +55,97 @@ func TestSession_QueryRows(t *testing.T) {
 		t.Fatal("failed to query db", err)
 	}
 }
+
+func TestSession_NamedArgs(t *testing.T) {
+	s := testRecordInit(t)
+	var name string
+	row := s.Raw("SELECT Name FROM User WHERE Name = @name OR Name = @name", sql.Named("name", "Tom")).QueryRow()
+	if err := row.Scan(&name); err != nil || name != "Tom" {
+		t.Fatal("failed to query with named args", err)
+	}
+
+	var users []User
+	if err := s.Where("Age >= @min AND Age <= @max", map[string]interface{}{"min": 18, "max": 20}).Find(&users); err != nil || len(users) != 1 {
+		t.Fatal("failed to query with map args", err)
+	}
+	if _, err := s.Raw("DELETE FROM User WHERE Name = @Name", User{Name: "Sam"}).Exec(); err != nil {
+		t.Fatal("failed to exec with struct args", err)
+	}
+	if _, err := s.Raw("DELETE FROM User WHERE Name = @name", sql.Named("other", "Tom")).Exec(); err == nil {
+		t.Fatal("expect error for missing named argument")
+	}
+}
+
+type AgeStat struct {
+	Age   int
+	Total int64
+	// Extra 和 Meta 没有对应的列类型，Scan 不会把结构体解析为模型
+	Extra interface{}
+	Meta  map[string]interface{}
+}
+
+func TestSession_Scan(t *testing.T) {
+	s := testRecordInit(t)
+	var user User
+	if err := s.Raw("SELECT * FROM User WHERE Name = ?", "Tom").Scan(&user); err != nil || user != *user1 {
+		t.Fatal("failed to scan struct", err, user)
+	}
+	if err := s.Raw("SELECT * FROM User WHERE Name = ?", "Jack").Scan(&user); err != ErrRecordNotFound {
+		t.Fatal("expect ErrRecordNotFound, but got", err)
+	}
+
+	var users []*User
+	if err := s.Raw("SELECT Name, Age, 1 AS Extra FROM User ORDER BY Age").Scan(&users); err != nil || len(users) != 2 || *users[1] != *user2 {
+		t.Fatal("failed to scan struct slice", err)
+	}
+	if err := s.Raw("SELECT Name, Age, 1 AS Extra FROM User").DisallowUnknownColumns().Scan(&users); err == nil {
+		t.Fatal("expect error for unknown column")
+	}
+
+	var stats []AgeStat
+	if err := s.Raw("SELECT Age, COUNT(*) AS Total FROM User GROUP BY Age ORDER BY Age").Scan(&stats); err != nil ||
+		len(stats) != 2 || stats[0].Age != 18 || stats[0].Total != 1 {
+		t.Fatal("failed to scan into struct without table", err, stats)
+	}
+	if s.RefTable().Name != "User" {
+		t.Fatal("expect model unchanged after scan, but got", s.RefTable().Name)
+	}
+
+	row := map[string]interface{}{}
+	if err := s.Raw("SELECT Name FROM User WHERE Age = ?", 25).Scan(&row); err != nil || row["Name"] != "Sam" {
+		t.Fatal("failed to scan map", err, row)
+	}
+	var rows []map[string]interface{}
+	if err := s.Raw("SELECT * FROM User").Scan(&rows); err != nil || len(rows) != 2 {
+		t.Fatal("failed to scan map slice", err)
+	}
+
+	var names []string
+	if err := s.Raw("SELECT Name FROM User ORDER BY Name").Scan(&names); err != nil || len(names) != 2 || names[0] != "Sam" {
+		t.Fatal("failed to scan primitive slice", err, names)
+	}
+	var count int64
+	if err := s.Raw("SELECT COUNT(*) FROM User").Scan(&count); err != nil || count != 2 {
+		t.Fatal("failed to scan primitive", err)
+	}
+	if err := s.Raw("SELECT Name, Age FROM User").Scan(&names); err == nil {
+		t.Fatal("expect error for scanning 2 columns into a primitive slice")
+	}
+}
+
+// dollarDialect 使用 $n 作为占位符，用于测试 Raw 的占位符编号
+type dollarDialect struct {
+	dialect.Dialect
+}
+
+func (dollarDialect) BindVar(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func TestSession_RawBindVar(t *testing.T) {
+	s := NewSession(TestDB, dollarDialect{TestDial})
+	s.Raw("SELECT * FROM User WHERE Name = ?", "Tom").Raw("AND Age = ?", 18).Raw("OR Name = @name", sql.Named("name", "Sam"))
+	if sql := s.sql.String(); sql != "SELECT * FROM User WHERE Name = $1 AND Age = $2 OR Name = $3 " {
+		t.Fatal("failed to number placeholders across Raw calls", sql)
+	}
+}
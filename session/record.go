@@ -1,24 +1,51 @@
 package session
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
 	"go-orm/clause"
 	"reflect"
 )
 
+// ErrRecordNotFound 表示 First 没有查询到任何记录
+var ErrRecordNotFound = errors.New("not found")
+
 // Insert 参数是对象指针，可以插入多个
 // session.Insert(&user1, &user2)
+// 模型上有关联字段时，关联记录会在同一个事务中一起插入
 func (s *Session) Insert(values ...interface{}) (int64, error) {
+	if len(values) > 0 && len(s.model(values[0]).RefTable().Relationships) > 0 {
+		return s.insertWithAssociations(values...)
+	}
+	return s.insert(values...)
+}
+
+func (s *Session) insert(values ...interface{}) (int64, error) {
+	if len(values) == 0 {
+		return 0, nil
+	}
+	// 一条 INSERT 语句只能插入同一个模型的记录
+	for _, value := range values {
+		if modelType(value) != modelType(values[0]) {
+			s.Clear()
+			return 0, fmt.Errorf("insert values must be of the same model, got %s and %s", modelType(values[0]), modelType(value))
+		}
+	}
 	recordValues := make([]interface{}, 0)
 	for _, value := range values {
 
 		// hooks： 执行 value 对象上挂载的 BeforeInsert方法
 		s.CallMethod(BeforeInsert, value)
 
-		table := s.Model(value).RefTable()
+		table := s.model(value).RefTable()
+		// 乐观锁：版本字段从 1 开始
+		s.initVersion(value)
+		// 自动写入 CreatedAt 和 UpdatedAt
+		s.setCreateTime(value)
 		// 构造 Insert子语句
 		// 如果插入多个对象，会执行多次，但是set的结果是相同的
-		s.clause.Set(clause.INSERT, table.Name, table.FieldNames)
+		s.clause.Set(clause.INSERT, s.quote(table.Name), s.quoteAll(table.FieldNames))
 		// 从对象中提取出符合schema定义的value
 		recordValues = append(recordValues, table.RecordValues(value))
 	}
@@ -27,54 +54,171 @@ func (s *Session) Insert(values ...interface{}) (int64, error) {
 	s.clause.Set(clause.VALUES, recordValues...)
 	// 调用一次 clause.Build() 按照传入的顺序构造出最终的 SQL 语句
 	sql, vars := s.clause.Build(clause.INSERT, clause.VALUES)
+	if s.upsert != "" {
+		sql += " " + s.upsert
+	}
 	// 执行完整的sql获取结果
 	result, err := s.Raw(sql, vars...).Exec()
 	if err != nil {
 		return 0, err
 	}
 
+	// 只插入一条记录时，用自增 ID 回填为零值的整数主键
+	if len(values) == 1 {
+		s.setLastInsertID(values[0], result)
+	}
+
 	s.CallMethod(AfterInsert, nil)
 	return result.RowsAffected()
 }
 
+func (s *Session) setLastInsertID(value interface{}, result sql.Result) {
+	pk := s.RefTable().PrimaryField
+	if pk == nil {
+		return
+	}
+	fv := reflect.Indirect(reflect.ValueOf(value)).FieldByName(pk.Name)
+	if !fv.CanSet() || !fv.IsZero() || !(fv.CanInt() || fv.CanUint()) {
+		return
+	}
+	if id, err := result.LastInsertId(); err == nil && id > 0 {
+		fv.Set(reflect.ValueOf(id).Convert(fv.Type()))
+	}
+}
+
+// Find 查询所有满足条件的记录并追加到 values 指向的切片中
+// 加载的对象会记录快照，之后 Save 只更新发生变化的字段
 func (s *Session) Find(values interface{}) error {
-	s.CallMethod(BeforeQuery, nil)
+	return s.find(values, true)
+}
+
+// find 与 Find 相同，track 为 false 时不记录快照，用于 FindInBatches 等可能加载大量记录的场景
+func (s *Session) find(values interface{}, track bool) error {
 	// 利用反射获取value的反射值和元素类型
 	destSlice := reflect.Indirect(reflect.ValueOf(values))
 	destType := destSlice.Type().Elem()
 	// 通过值和类型，创建新表
-	table := s.Model(reflect.New(destType).Elem().Interface()).RefTable()
+	table := s.model(reflect.New(destType).Elem().Interface()).RefTable()
+	// 执行查询后语句状态会被清空，提前取出需要预加载的关联
+	preloads := s.preloads
 
-	s.clause.Set(clause.SELECT, table.Name, table.FieldNames)
-	// 需要补充其他WHERE，ORDERBY，LIMIT的子语句，需要提前set好
-	sql, vars := s.clause.Build(clause.SELECT, clause.WHERE, clause.ORDERBY, clause.LIMIT)
-	rows, err := s.Raw(sql, vars...).QueryRows()
+	rows, err := s.Rows()
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
 
 	//遍历查询结果并填充values切片中
 	for rows.Next() {
 		dest := reflect.New(destType).Elem()
-		var values []interface{}
-		for _, name := range table.FieldNames {
-			values = append(values, dest.FieldByName(name).Addr().Interface())
-		}
-
-		err := rows.Scan(values...)
-		if err != nil {
+		if err := s.ScanRow(rows, dest.Addr().Interface()); err != nil {
 			return err
 		}
-
-		s.CallMethod(AfterQuery, dest.Addr().Interface())
+		if track {
+			// 记录加载时的字段值，Save 时只更新变化的字段
+			s.snapshot(dest.Addr().Interface())
+		}
 		destSlice.Set(reflect.Append(destSlice, dest))
 	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
 
-	return rows.Close()
+	if len(preloads) == 0 {
+		return nil
+	}
+	return s.preloadAll(destSlice, table, preloads)
+}
+
+// Rows 按照当前设置的条件查询 Model 对应的表，返回的 rows 需要由调用方关闭
+// 配合 ScanRow 可以逐行读取结果，不需要把所有记录放入内存
+// Select 和 OrderBy 中的列名在这里根据模型校验，不存在的列返回错误
+func (s *Session) Rows() (*sql.Rows, error) {
+	s.CallMethod(BeforeQuery, nil)
+	table := s.RefTable()
+	columns, vars, err := s.selectColumns()
+	if err == nil {
+		err = s.setOrderBy()
+	}
+	if err != nil {
+		s.Clear()
+		return nil, err
+	}
+	s.clause.Set(clause.SELECT, append([]interface{}{s.quote(table.Name), columns}, vars...)...)
+	s.scopeSoftDelete()
+	// 需要补充其他WHERE，ORDERBY，LIMIT的子语句，需要提前set好
+	sql, vars := s.clause.Build(clause.SELECT, clause.WHERE, clause.ORDERBY, clause.LIMIT)
+	return s.Raw(sql, vars...).QueryRows()
+}
+
+// ScanRow 把 Rows 返回的当前行扫描到结构体指针 value 中，并调用 value 上的 AfterQuery hook
+// 结果中的列按照列名对应到字段上，没有对应字段的列被忽略
+// ScanRow 不记录快照，逐行读取大量记录时内存占用不会随结果集增长
+func (s *Session) ScanRow(rows *sql.Rows, value interface{}) error {
+	table := s.model(value).RefTable()
+	dest := reflect.Indirect(reflect.ValueOf(value))
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	var values []interface{}
+	var nulls []nullField
+	for _, column := range columns {
+		field := table.GetField(column)
+		if field == nil {
+			values = append(values, new(interface{}))
+			continue
+		}
+		fv := dest.FieldByName(field.Name)
+		if scanner, ok := field.Scanner(fv); ok {
+			values = append(values, scanner)
+			continue
+		}
+		// 实现了 sql.Scanner 的类型自己处理 NULL
+		if field.Nullable || fv.Addr().Type().Implements(scannerType) {
+			values = append(values, fv.Addr().Interface())
+			continue
+		}
+		// 其他不可为 NULL 的字段先扫描到 *T，查询结果中出现 NULL 时写入零值而不是报错
+		ptr := reflect.New(reflect.PointerTo(fv.Type()))
+		values = append(values, ptr.Interface())
+		nulls = append(nulls, nullField{fv, ptr.Elem()})
+	}
+	if err := rows.Scan(values...); err != nil {
+		return err
+	}
+	for _, n := range nulls {
+		n.assign()
+	}
+
+	s.CallMethod(AfterQuery, value)
+	return nil
+}
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// nullField 是不可为 NULL 的字段和扫描时使用的 *T
+type nullField struct {
+	field reflect.Value
+	ptr   reflect.Value
+}
+
+func (n nullField) assign() {
+	if n.ptr.IsNil() {
+		n.field.SetZero()
+	} else {
+		n.field.Set(n.ptr.Elem())
+	}
 }
 
 // Update 接受 2 种入参，平铺开来的键值对和 map 类型的键值对
+// 模型有版本字段时，Model 指定的对象作为乐观锁检查的目标，版本号不匹配时返回 ErrStaleObject
+// s.Model(&doc).Update("Title", "final")
 func (s *Session) Update(kv ...interface{}) (int64, error) {
+	var target interface{}
+	if s.target != nil && s.RefTable().VersionField != nil {
+		target = s.target
+	}
 	s.CallMethod(BeforeUpdate, nil)
 	// 判断传入参数的类型
 	m, ok := kv[0].(map[string]interface{})
@@ -85,21 +229,148 @@ func (s *Session) Update(kv ...interface{}) (int64, error) {
 			m[kv[i].(string)] = kv[i+1]
 		}
 	}
+	return s.update(m, nil, target)
+}
+
+// Updates 接受结构体指针或 map 类型的键值对
+// 结构体只更新非零值字段，Select 可以强制更新零值字段，Omit 可以排除字段
+// 通过 Model 指定的对象主键有值时，只更新这一条记录，没有指定时使用传入结构体的主键
+// s.Model(&u).Updates(&User{Age: 30})
+func (s *Session) Updates(value interface{}) (int64, error) {
+	// 执行语句后目标对象会被清空，提前取出
+	target := s.target
+	if m, ok := value.(map[string]interface{}); ok {
+		s.CallMethod(BeforeUpdate, nil)
+		return s.update(m, nil, target)
+	}
+
+	if target == nil {
+		s.model(value)
+		target = value
+	}
+	// hooks：BeforeUpdate 可以在提取字段之前修改结构体
+	s.CallMethod(BeforeUpdate, value)
+
+	table := s.RefTable()
+	for _, name := range s.selects {
+		if table.GetField(name) == nil {
+			s.Clear()
+			return 0, fmt.Errorf("column %s not found in %s", name, table.Name)
+		}
+	}
+	dest := reflect.Indirect(reflect.ValueOf(value))
+	m := make(map[string]interface{})
+	for _, field := range table.Fields {
+		fv := dest.FieldByName(field.Name)
+		// 指定了 Select 时只更新 Select 的字段，否则只更新非零值字段
+		if len(s.selects) > 0 {
+			if !contains(s.selects, field.Name) {
+				continue
+			}
+		} else if fv.IsZero() {
+			continue
+		}
+		m[field.Name] = fv.Interface()
+	}
+	return s.update(m, value, target)
+}
+
+// update 将键值对中的字段名映射为列名，补充自动更新时间字段后执行 UPDATE，不存在的列返回错误
+// value 不为 nil 时，AfterUpdate 在 value 上调用
+// target 是被更新的对象，不为 nil 时追加主键条件，并对版本字段做乐观锁检查
+func (s *Session) update(kv map[string]interface{}, value, target interface{}) (int64, error) {
+	table := s.RefTable()
+	m := make(map[string]interface{}, len(kv))
+	for key, v := range kv {
+		field := table.GetField(key)
+		if field == nil {
+			s.Clear()
+			return 0, fmt.Errorf("column %s not found in %s", key, table.Name)
+		}
+		key = field.Name
+		// kv 中是字段的原始值，在这里统一转换为写入的值，例如加密、编码，clause.Expr 原样交给生成器
+		if _, isExpr := v.(clause.Expr); v != nil && !isExpr {
+			v = field.ValueOf(reflect.ValueOf(v))
+		}
+		if contains(s.omits, key) {
+			continue
+		}
+		m[key] = v
+	}
+	if len(m) == 0 {
+		s.Clear()
+		return 0, nil
+	}
+
+	// 自动更新 UpdatedAt 等字段，同时回写到传入的结构体上
+	s.setUpdateTime(m, value)
+
+	s.scopeSoftDelete()
+	var version *lockVersion
+	if target != nil {
+		s.wherePrimaryKey(target)
+		version = s.checkVersion(target, m)
+	}
+	s.increaseVersion(m)
 
-	s.clause.Set(clause.UPDATE, s.RefTable().Name, m)
+	s.clause.Set(clause.UPDATE, s.quote(table.Name), s.quoteKeys(m))
 	sql, vars := s.clause.Build(clause.UPDATE, clause.WHERE)
 	result, err := s.Raw(sql, vars...).Exec()
 	if err != nil {
 		return 0, err
 	}
-	s.CallMethod(AfterUpdate, nil)
-	return result.RowsAffected()
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if version != nil {
+		if affected == 0 {
+			return 0, ErrStaleObject
+		}
+		version.commit()
+	}
+	s.CallMethod(AfterUpdate, value)
+	return affected, nil
+}
+
+// wherePrimaryKey 当 value 的主键不是零值时，追加主键条件
+func (s *Session) wherePrimaryKey(value interface{}) {
+	pk := s.RefTable().PrimaryField
+	dest := reflect.Indirect(reflect.ValueOf(value))
+	if pk == nil || dest.Kind() != reflect.Struct {
+		return
+	}
+	if pv := dest.FieldByName(pk.Name); !pv.IsZero() {
+		s.clause.AndWhere(s.quote(pk.Name)+" = ?", pv.Interface())
+	}
 }
 
+// Delete 删除满足条件的记录
+// 表中有软删除字段时，只把 DeletedAt 设置为当前时间，使用 HardDelete 才会真正删除
 func (s *Session) Delete() (int64, error) {
+	table := s.RefTable()
+	if table.DeletedAtField == nil {
+		return s.HardDelete()
+	}
 	s.CallMethod(BeforeDelete, nil)
 
-	s.clause.Set(clause.DELETE, s.RefTable().Name)
+	s.scopeSoftDelete()
+	s.clause.Set(clause.UPDATE, s.quote(table.Name), map[string]interface{}{s.quote(table.DeletedAtField.Name): s.now()})
+	sql, vars := s.clause.Build(clause.UPDATE, clause.WHERE)
+	result, err := s.Raw(sql, vars...).Exec()
+	if err != nil {
+		return 0, err
+	}
+
+	s.CallMethod(AfterDelete, nil)
+	return result.RowsAffected()
+}
+
+// HardDelete 从表中真正删除满足条件的记录，包括已经被软删除的记录
+func (s *Session) HardDelete() (int64, error) {
+	s.CallMethod(BeforeDelete, nil)
+
+	s.clause.Set(clause.DELETE, s.quote(s.RefTable().Name))
 	sql, vars := s.clause.Build(clause.DELETE, clause.WHERE)
 	result, err := s.Raw(sql, vars...).Exec()
 	if err != nil {
@@ -111,7 +382,12 @@ func (s *Session) Delete() (int64, error) {
 }
 
 func (s *Session) Count() (int64, error) {
-	s.clause.Set(clause.COUNT, s.RefTable().Name)
+	if err := s.err; err != nil {
+		s.Clear()
+		return 0, err
+	}
+	s.clause.Set(clause.COUNT, s.quote(s.RefTable().Name))
+	s.scopeSoftDelete()
 	sql, vars := s.clause.Build(clause.COUNT, clause.WHERE)
 	row := s.Raw(sql, vars...).QueryRow()
 
@@ -123,22 +399,93 @@ func (s *Session) Count() (int64, error) {
 	return tmp, nil
 }
 
+// Unscoped 使当前语句不再自动过滤软删除的记录
+func (s *Session) Unscoped() *Session {
+	s.unscoped = true
+	return s
+}
+
+// scopeSoftDelete 表中有软删除字段时，追加 DeletedAt IS NULL 条件
+func (s *Session) scopeSoftDelete() {
+	if field := s.RefTable().DeletedAtField; field != nil && !s.unscoped {
+		s.clause.AndWhere(s.quote(field.Name) + " IS NULL")
+	}
+}
+
 func (s *Session) Limit(num int) *Session {
 	s.clause.Set(clause.LIMIT, num)
 	return s
 }
 
-func (s *Session) Where(desc string, args ...interface{}) *Session {
-	var vars []interface{}
-	s.clause.Set(clause.WHERE, append(append(vars, desc), args...)...)
+// Where 多次调用时，条件之间以 AND 连接
+// desc 是条件语句或者 clause.Expr，args 中的 clause.Expr 会替换对应的 ?，不作为参数绑定
+// 和 Raw 一样支持 @name 形式的命名参数，例如 s.Where("Age > @age", map[string]interface{}{"age": 18})
+func (s *Session) Where(desc interface{}, args ...interface{}) *Session {
+	var sql string
+	switch v := desc.(type) {
+	case string:
+		sql = v
+	case clause.Expr:
+		sql, args = v.SQL, append(append([]interface{}{}, v.Vars...), args...)
+	default:
+		return s.setErr(fmt.Errorf("invalid where %v of type %T", desc, desc))
+	}
+	// 条件会和其他子句拼接后再交给 Raw，这里统一使用 ?，由 Raw 改写为 Dialect 的占位符
+	sql, args, err := clause.BindNamed(sql, args, nil)
+	if err != nil {
+		return s.setErr(err)
+	}
+	s.clause.AndWhere(sql, args...)
 	return s
 }
 
-func (s *Session) OrderBy(desc string) *Session {
-	s.clause.Set(clause.ORDERBY, desc)
+// OrderBy 追加排序，desc 是 "Age DESC, Name" 形式的字符串或者 clause.Expr
+// 字符串中的列名在查询时根据模型校验并加上引号，避免把用户输入直接拼接到 SQL 中
+func (s *Session) OrderBy(desc interface{}) *Session {
+	s.orders = append(s.orders, desc)
 	return s
 }
 
+// Select 指定查询的列，或者 Updates 需要更新的字段，零值字段也会被更新
+// 字符串是模型上的字段名，clause.Expr 原样拼接到 SELECT 的列中，对 Updates 不生效
+func (s *Session) Select(fields ...interface{}) *Session {
+	for _, field := range fields {
+		switch v := field.(type) {
+		case string:
+			s.selects = append(s.selects, v)
+		case clause.Expr:
+			s.selectExprs = append(s.selectExprs, v)
+		default:
+			s.setErr(fmt.Errorf("invalid select %v of type %T", field, field))
+		}
+	}
+	return s
+}
+
+// Omit 指定 Updates 时需要忽略的字段
+func (s *Session) Omit(fields ...string) *Session {
+	s.omits = append(s.omits, fields...)
+	return s
+}
+
+// quoteKeys 返回列名加上引号之后的键值对
+func (s *Session) quoteKeys(m map[string]interface{}) map[string]interface{} {
+	quoted := make(map[string]interface{}, len(m))
+	for key, v := range m {
+		quoted[s.quote(key)] = v
+	}
+	return quoted
+}
+
+func contains(list []string, target string) bool {
+	for _, v := range list {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
 // First 根据传入的类型，利用反射构造切片
 // 调用 Limit(1) 限制返回的行数，调用 Find 方法获取到查询结果
 func (s *Session) First(values interface{}) error {
@@ -151,7 +498,7 @@ func (s *Session) First(values interface{}) error {
 	}
 
 	if destSlice.Len() == 0 {
-		return errors.New("not found")
+		return ErrRecordNotFound
 	}
 	dest.Set(destSlice.Index(0))
 	return nil
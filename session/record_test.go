@@ -1,7 +1,13 @@
 package session
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"go-orm/clause"
+	"go-orm/dialect"
 	"testing"
+	"time"
 )
 
 type User struct {
@@ -72,3 +78,193 @@ func TestSession_DeleteAndCount(t *testing.T) {
 		t.Fatal("failed to delete or count")
 	}
 }
+
+type Cond string
+
+// 不支持的条件和列类型不会被忽略，否则语句会作用于整张表
+func TestSession_InvalidWhere(t *testing.T) {
+	s := testRecordInit(t)
+	if _, err := s.Where(Cond("Name = 'Tom'")).Delete(); err == nil {
+		t.Fatal("expect error for invalid where")
+	}
+	if count, _ := s.Count(); count != 2 {
+		t.Fatal("expect no record deleted, but got count", count)
+	}
+	var users []User
+	if err := s.Select(1).Find(&users); err == nil {
+		t.Fatal("expect error for invalid select")
+	}
+}
+
+func TestSession_Updates(t *testing.T) {
+	s := testRecordInit(t)
+	affected, _ := s.Model(&User{Name: "Tom"}).Updates(&User{Age: 30})
+	u := &User{}
+	_ = s.Where("Name = ?", "Tom").First(u)
+	if affected != 1 || u.Age != 30 {
+		t.Fatal("failed to update non-zero fields")
+	}
+
+	affected, _ = s.Model(&User{Name: "Tom"}).Select("Age").Updates(&User{})
+	_ = s.Where("Name = ?", "Tom").First(u)
+	if affected != 1 || u.Age != 0 {
+		t.Fatal("failed to update selected zero-value fields")
+	}
+}
+
+// 之前语句使用过的对象不会限定 Updates 更新的范围
+func TestSession_UpdatesWithoutModel(t *testing.T) {
+	s := testRecordInit(t)
+	if affected, err := s.Where("Name = ?", "Tom").Updates(map[string]interface{}{"Age": 31}); err != nil || affected != 1 {
+		t.Fatal("expect 1 row updated by where condition, but got", affected, err)
+	}
+	if affected, err := s.Updates(&User{Age: 40}); err != nil || affected != 2 {
+		t.Fatal("expect all rows updated, but got", affected, err)
+	}
+}
+
+type Customer struct {
+	Name      string `go-orm:"PRIMARY KEY"`
+	DeletedAt *time.Time
+}
+
+func TestSession_SoftDelete(t *testing.T) {
+	s := NewTestSession().Model(&Customer{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	_, _ = s.Insert(&Customer{Name: "Tom"}, &Customer{Name: "Sam"})
+
+	affected, _ := s.Where("Name = ?", "Tom").Delete()
+	count, _ := s.Count()
+	all, _ := s.Unscoped().Count()
+	if affected != 1 || count != 1 || all != 2 {
+		t.Fatal("failed to soft delete")
+	}
+
+	var customers []Customer
+	if err := s.Unscoped().Where("Name = ?", "Tom").Find(&customers); err != nil || len(customers) != 1 || customers[0].DeletedAt == nil {
+		t.Fatal("failed to find soft deleted record")
+	}
+
+	affected, _ = s.Where("Name = ?", "Tom").HardDelete()
+	all, _ = s.Unscoped().Count()
+	if affected != 1 || all != 1 {
+		t.Fatal("failed to hard delete")
+	}
+}
+
+type Post struct {
+	Title     string `go-orm:"PRIMARY KEY"`
+	CreatedAt time.Time
+	UpdatedAt int64 `go-orm:"autoUpdateTime:milli"`
+}
+
+func TestSession_AutoTime(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewSession(TestDB, TestDial, WithNowFunc(func() time.Time { return now })).Model(&Post{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+
+	post := &Post{Title: "hello"}
+	_, _ = s.Insert(post)
+	if !post.CreatedAt.Equal(now) || post.UpdatedAt != now.UnixMilli() {
+		t.Fatal("failed to set timestamps on insert, got", post)
+	}
+
+	now = now.Add(time.Hour)
+	_, _ = s.Model(post).Updates(&Post{Title: "hello"})
+	p := &Post{}
+	_ = s.First(p)
+	if p.UpdatedAt != now.UnixMilli() || p.CreatedAt.Equal(now) {
+		t.Fatal("failed to set UpdatedAt on update, got", p)
+	}
+}
+
+type Contact struct {
+	ID    int `go-orm:"PRIMARY KEY"`
+	Phone *string
+	Email sql.NullString
+	Note  string `go-orm:"NULL"`
+}
+
+func TestSession_FindNullable(t *testing.T) {
+	s := NewTestSession().Model(&Contact{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	phone := "123456"
+	_, err := s.Insert(&Contact{ID: 1, Phone: &phone, Email: sql.NullString{String: "a@b.c", Valid: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = s.Raw("INSERT INTO Contact(ID) VALUES (2)").Exec()
+
+	var contacts []Contact
+	if err := s.OrderBy("ID").Find(&contacts); err != nil || len(contacts) != 2 {
+		t.Fatal("failed to find nullable records", err)
+	}
+	if *contacts[0].Phone != phone || contacts[0].Email.String != "a@b.c" {
+		t.Fatal("failed to scan non-null values", contacts[0])
+	}
+	if contacts[1].Phone != nil || contacts[1].Email.Valid || contacts[1].Note != "" {
+		t.Fatal("failed to scan null values", contacts[1])
+	}
+}
+
+type Cents struct {
+	Amount int64
+}
+
+func (c Cents) Value() (driver.Value, error) {
+	return c.Amount, nil
+}
+
+func (c *Cents) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case int64:
+		c.Amount = v
+	case nil:
+		c.Amount = 0
+	default:
+		return fmt.Errorf("can not scan %T into Cents", src)
+	}
+	return nil
+}
+
+type Tracking string
+
+type Order struct {
+	ID       int `go-orm:"PRIMARY KEY"`
+	Price    Cents
+	Tracking Tracking
+}
+
+func TestSession_CustomTypes(t *testing.T) {
+	types := &dialect.Types{}
+	types.Register(Tracking(""), "CHAR(12)")
+	s := NewSession(TestDB, TestDial, WithTypes(types)).Model(&Order{})
+	if field := s.RefTable().GetField("Tracking"); field.Type != "CHAR(12)" {
+		t.Fatal("failed to use registered type", field.Type)
+	}
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	if _, err := s.Insert(&Order{ID: 1, Price: Cents{1999}, Tracking: "SF1234567890"}); err != nil {
+		t.Fatal(err)
+	}
+	order := &Order{}
+	if err := s.First(order); err != nil || order.Price.Amount != 1999 || order.Tracking != "SF1234567890" {
+		t.Fatal("failed to round-trip custom types", order, err)
+	}
+}
+
+func TestSession_UpdateExpr(t *testing.T) {
+	s := testRecordInit(t)
+	affected, err := s.Where("Name = ?", "Tom").Update("Age", clause.Expr{SQL: "Age + ?", Vars: []interface{}{2}})
+	if err != nil || affected != 1 {
+		t.Fatal("failed to update with expr", err)
+	}
+	u := &User{}
+	_ = s.Where(clause.Expr{SQL: "Age = ?", Vars: []interface{}{20}}).First(u)
+	if u.Name != "Tom" {
+		t.Fatal("failed to query with expr", u)
+	}
+}
@@ -0,0 +1,189 @@
+package session
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// DisallowUnknownColumns 使 Scan 在查询结果中有结构体上不存在的列时返回错误，默认忽略这些列
+func (s *Session) DisallowUnknownColumns() *Session {
+	s.disallowUnknown = true
+	return s
+}
+
+// Scan 执行 Raw 设置的查询，并把结果写入 dest，dest 必须是指针，可以指向
+//   - 结构体：写入第一行，列按照字段名对应，没有结果时返回 ErrRecordNotFound
+//   - map[string]interface{}：写入第一行，[]byte 类型的值转换为 string
+//   - 基本类型，例如 int64、string：查询结果只能有一列，写入第一行
+//   - 以上类型或其指针的切片：写入所有行，切片原有的元素会被清空
+//
+// 结构体不需要是模型，不会被解析为 Schema，也不会改变 Session 的 Model，
+// 因此 serializer、encrypt 等标签和 AfterQuery hook 不生效，读取模型时使用 Find
+//
+// s.Raw("SELECT Name, COUNT(*) AS Total FROM `Order` GROUP BY Name").Scan(&stats)
+func (s *Session) Scan(dest interface{}) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		s.Clear()
+		return errors.New("scan destination must be a non-nil pointer")
+	}
+	// 执行查询后语句状态会被清空，提前取出配置
+	strict := s.disallowUnknown
+	rows, err := s.QueryRows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	target := rv.Elem()
+	if target.Kind() != reflect.Slice || target.Type().Elem().Kind() == reflect.Uint8 {
+		scan, err := scanFunc(target.Type(), columns, strict)
+		if err != nil {
+			return err
+		}
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			return ErrRecordNotFound
+		}
+		if err := scan(rows, target); err != nil {
+			return err
+		}
+		return rows.Close()
+	}
+
+	elemType := target.Type().Elem()
+	valueType := elemType
+	if elemType.Kind() == reflect.Ptr {
+		valueType = elemType.Elem()
+	}
+	scan, err := scanFunc(valueType, columns, strict)
+	if err != nil {
+		return err
+	}
+	target.Set(reflect.MakeSlice(target.Type(), 0, 0))
+	for rows.Next() {
+		elem := reflect.New(elemType).Elem()
+		v := elem
+		if elemType.Kind() == reflect.Ptr {
+			elem.Set(reflect.New(valueType))
+			v = elem.Elem()
+		}
+		if err := scan(rows, v); err != nil {
+			return err
+		}
+		target.Set(reflect.Append(target, elem))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// scanFunc 返回把 rows 的当前行写入 typ 类型的可寻址值的函数，结构体的列与字段的对应关系只计算一次
+func scanFunc(typ reflect.Type, columns []string, strict bool) (func(*sql.Rows, reflect.Value) error, error) {
+	switch {
+	case typ.Kind() == reflect.Map && typ.Key().Kind() == reflect.String:
+		return func(rows *sql.Rows, v reflect.Value) error {
+			return scanMap(rows, columns, v)
+		}, nil
+	case isModel(typ):
+		indexes, err := fieldIndexes(typ, columns, strict)
+		if err != nil {
+			return nil, err
+		}
+		return func(rows *sql.Rows, v reflect.Value) error {
+			return scanStruct(rows, indexes, v)
+		}, nil
+	}
+	if len(columns) != 1 {
+		return nil, fmt.Errorf("expect 1 column to scan into %s, got %d", typ, len(columns))
+	}
+	return func(rows *sql.Rows, v reflect.Value) error {
+		return rows.Scan(v.Addr().Interface())
+	}, nil
+}
+
+// fieldIndexes 按照与 Schema 相同的规则，把列名对应到结构体上同名的导出字段，匿名字段不参与匹配
+// 没有对应字段的列为 -1，strict 为 true 时返回错误
+func fieldIndexes(typ reflect.Type, columns []string, strict bool) ([]int, error) {
+	indexes := make([]int, len(columns))
+	for i, column := range columns {
+		indexes[i] = -1
+		if f, ok := typ.FieldByName(column); ok && len(f.Index) == 1 && !f.Anonymous && f.IsExported() {
+			indexes[i] = f.Index[0]
+		} else if strict {
+			return nil, fmt.Errorf("unknown column %s for %s", column, typ.Name())
+		}
+	}
+	return indexes, nil
+}
+
+// scanStruct 把当前行写入结构体 v，与 ScanRow 一样，不可为 NULL 的字段遇到 NULL 时写入零值
+func scanStruct(rows *sql.Rows, indexes []int, v reflect.Value) error {
+	values := make([]interface{}, len(indexes))
+	var nulls []nullField
+	for i, index := range indexes {
+		if index < 0 {
+			values[i] = new(interface{})
+			continue
+		}
+		fv := v.Field(index)
+		if fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface || fv.Addr().Type().Implements(scannerType) {
+			values[i] = fv.Addr().Interface()
+			continue
+		}
+		ptr := reflect.New(reflect.PointerTo(fv.Type()))
+		values[i] = ptr.Interface()
+		nulls = append(nulls, nullField{fv, ptr.Elem()})
+	}
+	if err := rows.Scan(values...); err != nil {
+		return err
+	}
+	for _, n := range nulls {
+		n.assign()
+	}
+	return nil
+}
+
+// scanMap 以列名为 key 写入 map，驱动返回的 []byte 转换为 string
+func scanMap(rows *sql.Rows, columns []string, v reflect.Value) error {
+	values := make([]interface{}, len(columns))
+	for i := range values {
+		values[i] = new(interface{})
+	}
+	if err := rows.Scan(values...); err != nil {
+		return err
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(v.Type(), len(columns)))
+	}
+	for i, column := range columns {
+		value := *values[i].(*interface{})
+		if b, ok := value.([]byte); ok {
+			value = string(b)
+		}
+		rv := reflect.ValueOf(value)
+		if value == nil {
+			rv = reflect.Zero(v.Type().Elem())
+		} else if !rv.Type().AssignableTo(v.Type().Elem()) {
+			return fmt.Errorf("can not scan column %s of type %s into %s", column, rv.Type(), v.Type())
+		}
+		v.SetMapIndex(reflect.ValueOf(column).Convert(v.Type().Key()), rv)
+	}
+	return nil
+}
+
+// isModel 判断 typ 是否按照模型逐列扫描，time.Time 和实现了 sql.Scanner 的结构体作为单个值扫描
+func isModel(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Struct && typ != reflect.TypeOf(time.Time{}) &&
+		!reflect.PointerTo(typ).Implements(scannerType)
+}
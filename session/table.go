@@ -2,6 +2,7 @@ package session
 
 import (
 	"fmt"
+	"go-orm/dialect"
 	"go-orm/log"
 	"go-orm/schema"
 	"reflect"
@@ -9,14 +10,58 @@ import (
 )
 
 // Model 解析传入对象成Schema，保存到refTable中，继续返回s支持链式调用
+// value 同时作为当前这条语句的目标对象，Updates 根据它的主键只更新这一条记录
 func (s *Session) Model(value interface{}) *Session {
+	s.target = value
+	return s.model(value)
+}
+
+// model 切换 refTable，不设置语句的目标对象，供内部根据记录类型切换模型时使用
+func (s *Session) model(value interface{}) *Session {
 	// nil or a new model, update refTable
-	if s.refTable == nil || reflect.TypeOf(value) != reflect.TypeOf(s.refTable.Model) {
-		s.refTable = schema.Parse(value, s.dialect)
+	// 指针和结构体本身视为同一个模型
+	if s.refTable == nil || modelType(value) != modelType(s.refTable.Model) {
+		s.refTable = s.parse(value)
+		return s
 	}
+	// 类型相同时复用解析结果，只替换 Model 指向的对象
+	table := *s.refTable
+	table.Model = value
+	s.refTable = &table
 	return s
 }
 
+// parse 解析 value 的结构，设置了缓存时同一个类型只解析一次
+func (s *Session) parse(value interface{}) *schema.Schema {
+	if s.schemas == nil {
+		return schema.Parse(value, s.schemaDialect(), schema.WithKeyProvider(s.keys))
+	}
+	typ := modelType(value)
+	if cached, ok := s.schemas.Load(typ); ok {
+		table := *cached.(*schema.Schema)
+		table.Model = value
+		return &table
+	}
+	table := schema.Parse(value, s.schemaDialect(), schema.WithKeyProvider(s.keys))
+	// 缓存中保存一个指向零值的副本，避免长期引用调用方的对象
+	cached := *table
+	cached.Model = reflect.New(typ).Interface()
+	s.schemas.Store(typ, &cached)
+	return table
+}
+
+// schemaDialect 返回解析模型使用的 Dialect，注册过的自定义类型优先使用注册的列类型
+func (s *Session) schemaDialect() dialect.Dialect {
+	if s.types == nil {
+		return s.dialect
+	}
+	return dialect.WithTypes(s.dialect, s.types)
+}
+
+func modelType(value interface{}) reflect.Type {
+	return reflect.Indirect(reflect.ValueOf(value)).Type()
+}
+
 func (s *Session) RefTable() *schema.Schema {
 	if s.refTable == nil {
 		log.Error("Model is not set")
@@ -29,19 +74,36 @@ func (s *Session) CreateTable() error {
 
 	var columns []string
 	for _, field := range table.Fields {
-		columnDef := fmt.Sprintf("%s %s %s", field.Name, field.Type, field.Tag)
+		columnDef := fmt.Sprintf("%s %s %s", s.quote(field.Name), field.Type, field.Constraint())
 		columns = append(columns, columnDef)
 	}
 
 	desc := strings.Join(columns, ",")
-	sql := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s) ;", table.Name, desc)
+	sql := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s) ;", s.quote(table.Name), desc)
 
-	_, err := s.Raw(sql).Exec()
-	return err
+	if _, err := s.Raw(sql).Exec(); err != nil {
+		return err
+	}
+	return s.CreateJoinTables()
+}
+
+// CreateJoinTables 创建模型上多对多关联的连接表，表已经存在时跳过
+func (s *Session) CreateJoinTables() error {
+	for _, rel := range s.RefTable().Relationships {
+		if rel.JoinTable == nil {
+			continue
+		}
+		c := s.clone()
+		c.refTable = rel.JoinTable
+		if err := c.CreateTable(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *Session) DropTable() error {
-	sql := fmt.Sprintf("DROP TABLE IF EXISTS %s ;", s.RefTable().Name)
+	sql := fmt.Sprintf("DROP TABLE IF EXISTS %s ;", s.quote(s.RefTable().Name))
 	_, err := s.Raw(sql).Exec()
 	return err
 }
@@ -0,0 +1,46 @@
+package session
+
+import (
+	"reflect"
+	"time"
+)
+
+// now 返回当前时间，Engine 可以通过 WithNowFunc 注入时钟
+func (s *Session) now() time.Time {
+	if s.nowFunc != nil {
+		return s.nowFunc()
+	}
+	return time.Now()
+}
+
+// setCreateTime 插入记录前，为零值的自动时间戳字段写入当前时间
+func (s *Session) setCreateTime(value interface{}) {
+	dest := reflect.Indirect(reflect.ValueOf(value))
+	now := s.now()
+	for _, field := range s.RefTable().Fields {
+		if !field.AutoCreateTime && !field.AutoUpdateTime {
+			continue
+		}
+		if fv := dest.FieldByName(field.Name); fv.CanSet() && fv.IsZero() {
+			fv.Set(reflect.ValueOf(field.TimeValue(now)).Convert(fv.Type()))
+		}
+	}
+}
+
+// setUpdateTime 更新记录时，把自动更新时间字段放入待更新的键值对，并回写到 value 上
+func (s *Session) setUpdateTime(m map[string]interface{}, value interface{}) {
+	now := s.now()
+	for _, field := range s.RefTable().Fields {
+		if !field.AutoUpdateTime || contains(s.omits, field.Name) {
+			continue
+		}
+		v := field.TimeValue(now)
+		m[field.Name] = v
+		if value == nil {
+			continue
+		}
+		if fv := reflect.Indirect(reflect.ValueOf(value)).FieldByName(field.Name); fv.CanSet() {
+			fv.Set(reflect.ValueOf(v).Convert(fv.Type()))
+		}
+	}
+}
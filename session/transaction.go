@@ -1,28 +1,184 @@
 package session
 
-import "go-orm/log"
+import (
+	"database/sql"
+	"fmt"
+	"go-orm/log"
+)
 
+// Begin 开启事务，Session 已经处于事务中时创建一个保存点
 func (s *Session) Begin() (err error) {
+	return s.BeginTx(nil)
+}
+
+// BeginTx 使用指定的隔离级别和只读选项开启事务，opts 为 nil 时使用数据库默认配置
+// Session 已经处于事务中时创建一个保存点，opts 被忽略
+func (s *Session) BeginTx(opts *sql.TxOptions) (err error) {
+	if s.tx != nil {
+		name := fmt.Sprintf("sp_%d", len(s.savepoints)+1)
+		log.Info("transaction savepoint", name)
+		if _, err = s.tx.ExecContext(s.context(), s.dialect.SavepointSQL(name)); err != nil {
+			log.Error(err)
+			return
+		}
+		s.savepoints = append(s.savepoints, name)
+		s.callbacks = append(s.callbacks, &txCallbacks{})
+		return
+	}
+
 	log.Info("transaction begin")
-	if s.tx, err = s.db.Begin(); err != nil {
+	if s.tx, err = s.db.BeginTx(s.context(), opts); err != nil {
 		log.Error(err)
 		return
 	}
+	s.callbacks = append(s.callbacks, &txCallbacks{})
 	return
 }
 
+// Commit 提交事务，处于保存点中时只释放最近的保存点
+// 提交成功后执行 AfterCommit 注册的回调，释放保存点时回调交给外层事务
 func (s *Session) Commit() (err error) {
+	callbacks := s.popCallbacks()
+	if name, ok := s.popSavepoint(); ok {
+		log.Info("transaction release savepoint", name)
+		if _, err = s.tx.ExecContext(s.context(), s.dialect.ReleaseSavepointSQL(name)); err != nil {
+			log.Error(err)
+			callbacks.runAfterRollback()
+			return
+		}
+		s.callbacks[len(s.callbacks)-1].merge(callbacks)
+		return
+	}
+
 	log.Info("transaction commit")
 	if err = s.tx.Commit(); err != nil {
 		log.Error(err)
 	}
+	s.tx = nil
+	if err != nil {
+		callbacks.runAfterRollback()
+	} else {
+		callbacks.runAfterCommit()
+	}
 	return
 }
 
+// Rollback 回滚事务，处于保存点中时只回滚到最近的保存点，外层事务不受影响
+// 回滚后执行当前这一层 AfterRollback 注册的回调
 func (s *Session) Rollback() (err error) {
+	callbacks := s.popCallbacks()
+	defer callbacks.runAfterRollback()
+	if name, ok := s.popSavepoint(); ok {
+		log.Info("transaction rollback to savepoint", name)
+		if _, err = s.tx.ExecContext(s.context(), s.dialect.RollbackToSavepointSQL(name)); err != nil {
+			log.Error(err)
+		}
+		return
+	}
+
 	log.Info("transaction rollback")
 	if err = s.tx.Rollback(); err != nil {
 		log.Error(err)
 	}
+	s.tx = nil
+	return
+}
+
+func (s *Session) popSavepoint() (string, bool) {
+	if len(s.savepoints) == 0 {
+		return "", false
+	}
+	name := s.savepoints[len(s.savepoints)-1]
+	s.savepoints = s.savepoints[:len(s.savepoints)-1]
+	return name, true
+}
+
+// txCallbacks 是一层事务或保存点中注册的回调
+type txCallbacks struct {
+	afterCommit   []func()
+	afterRollback []func()
+}
+
+// AfterCommit 注册在事务提交成功后执行的回调，不在事务中时立即执行
+// 在保存点中注册的回调会等到最外层事务提交后执行，保存点被回滚时丢弃
+func (s *Session) AfterCommit(f func()) {
+	if len(s.callbacks) == 0 {
+		f()
+		return
+	}
+	c := s.callbacks[len(s.callbacks)-1]
+	c.afterCommit = append(c.afterCommit, f)
+}
+
+// AfterRollback 注册在事务回滚后执行的回调，不在事务中时忽略
+// 在保存点中注册的回调在回滚到该保存点或者外层事务回滚时执行
+func (s *Session) AfterRollback(f func()) {
+	if len(s.callbacks) == 0 {
+		return
+	}
+	c := s.callbacks[len(s.callbacks)-1]
+	c.afterRollback = append(c.afterRollback, f)
+}
+
+func (s *Session) popCallbacks() *txCallbacks {
+	if len(s.callbacks) == 0 {
+		return &txCallbacks{}
+	}
+	c := s.callbacks[len(s.callbacks)-1]
+	s.callbacks = s.callbacks[:len(s.callbacks)-1]
+	return c
+}
+
+func (c *txCallbacks) merge(other *txCallbacks) {
+	c.afterCommit = append(c.afterCommit, other.afterCommit...)
+	c.afterRollback = append(c.afterRollback, other.afterRollback...)
+}
+
+func (c *txCallbacks) runAfterCommit() {
+	for _, f := range c.afterCommit {
+		f()
+	}
+}
+
+func (c *txCallbacks) runAfterRollback() {
+	for _, f := range c.afterRollback {
+		f()
+	}
+}
+
+// Transaction 将所有的操作放到一个回调函数中执行，发生任何错误自动回滚，否则提交
+// Session 已经处于事务中时使用保存点，内层失败只回滚到保存点，不会中止外层事务
+func (s *Session) Transaction(f func(*Session) (interface{}, error)) (result interface{}, err error) {
+	return s.TransactionTx(nil, f)
+}
+
+// TransactionTx 与 Transaction 相同，但使用 opts 开启事务
+func (s *Session) TransactionTx(opts *sql.TxOptions, f func(*Session) (interface{}, error)) (result interface{}, err error) {
+	if err = s.BeginTx(opts); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = s.Rollback()
+			panic(p) // re-throw panic after Rollback
+		} else if err != nil {
+			_ = s.Rollback() // err is non-nil; don't change it
+		} else {
+			err = s.Commit() // err is nil; if Commit returns error update err
+		}
+	}()
+
+	return f(s)
+}
+
+// withTransaction 在事务中执行 f，发生错误或 panic 时回滚
+// Session 已经处于事务中时直接在当前事务中执行
+func (s *Session) withTransaction(f func() error) (err error) {
+	if s.tx != nil {
+		return f()
+	}
+	_, err = s.Transaction(func(*Session) (interface{}, error) {
+		return nil, f()
+	})
 	return
 }
@@ -0,0 +1,79 @@
+package session
+
+import (
+	"errors"
+	"go-orm/clause"
+	"reflect"
+)
+
+// ErrStaleObject 表示对象在加载之后已经被其他人修改，版本号不匹配导致没有更新任何行
+var ErrStaleObject = errors.New("stale object: version mismatch")
+
+// lockVersion 记录一次带版本检查的更新，更新成功后把新版本号写回对象
+type lockVersion struct {
+	field reflect.Value
+	next  int64
+}
+
+// checkVersion 当 target 的版本字段有值时，追加 Version = ? 条件，并把版本号加一放入待更新的键值对
+func (s *Session) checkVersion(target interface{}, m map[string]interface{}) *lockVersion {
+	vf := s.RefTable().VersionField
+	dest := reflect.Indirect(reflect.ValueOf(target))
+	if vf == nil || dest.Kind() != reflect.Struct {
+		return nil
+	}
+	fv := dest.FieldByName(vf.Name)
+	if fv.IsZero() {
+		return nil
+	}
+	current := versionOf(fv)
+	s.clause.AndWhere(s.quote(vf.Name)+" = ?", current)
+	m[vf.Name] = current + 1
+	return &lockVersion{field: fv, next: current + 1}
+}
+
+// increaseVersion 没有做版本检查的 Update 也把版本号加一，已经加载的对象 Save 时会得到 ErrStaleObject
+// 键值对中已经指定了版本字段时不做处理，例如 checkVersion 已经放入了新版本号
+func (s *Session) increaseVersion(m map[string]interface{}) {
+	vf := s.RefTable().VersionField
+	if vf == nil {
+		return
+	}
+	if _, ok := m[vf.Name]; ok {
+		return
+	}
+	m[vf.Name] = clause.Expr{SQL: s.quote(vf.Name) + " + ?", Vars: []interface{}{1}}
+}
+
+func (v *lockVersion) commit() {
+	if v.field.CanSet() {
+		setVersion(v.field, v.next)
+	}
+}
+
+// initVersion 插入记录时，把为零值的版本字段初始化为 1
+func (s *Session) initVersion(value interface{}) {
+	vf := s.RefTable().VersionField
+	if vf == nil {
+		return
+	}
+	fv := reflect.Indirect(reflect.ValueOf(value)).FieldByName(vf.Name)
+	if fv.CanSet() && fv.IsZero() {
+		setVersion(fv, 1)
+	}
+}
+
+func versionOf(v reflect.Value) int64 {
+	if v.CanInt() {
+		return v.Int()
+	}
+	return int64(v.Uint())
+}
+
+func setVersion(v reflect.Value, version int64) {
+	if v.CanInt() {
+		v.SetInt(version)
+		return
+	}
+	v.SetUint(uint64(version))
+}
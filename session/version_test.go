@@ -0,0 +1,72 @@
+package session
+
+import (
+	"errors"
+	"testing"
+)
+
+type Document struct {
+	ID      int `go-orm:"PRIMARY KEY"`
+	Title   string
+	Version int `go-orm:"version"`
+}
+
+func TestSession_OptimisticLock(t *testing.T) {
+	s := NewTestSession().Model(&Document{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	doc := &Document{ID: 1, Title: "draft"}
+	if _, err := s.Insert(doc); err != nil || doc.Version != 1 {
+		t.Fatal("failed to init version")
+	}
+
+	stale := &Document{}
+	_ = s.Where("ID = ?", 1).First(stale)
+
+	doc.Title = "final"
+	if _, err := s.Save(doc); err != nil || doc.Version != 2 {
+		t.Fatal("failed to increase version")
+	}
+
+	stale.Title = "conflict"
+	if _, err := s.Save(stale); !errors.Is(err, ErrStaleObject) {
+		t.Fatal("expect ErrStaleObject, but got", err)
+	}
+}
+
+func TestSession_UpdateIncreasesVersion(t *testing.T) {
+	s := NewTestSession().Model(&Document{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	_, _ = s.Insert(&Document{ID: 1, Title: "draft"})
+
+	loaded := &Document{}
+	_ = s.Where("ID = ?", 1).First(loaded)
+	if _, err := s.Model(&Document{}).Where("ID = ?", 1).Update("Title", "final"); err != nil {
+		t.Fatal(err)
+	}
+	doc := &Document{}
+	_ = s.Where("ID = ?", 1).First(doc)
+	if doc.Version != 2 {
+		t.Fatal("failed to increase version on update", doc.Version)
+	}
+	loaded.Title = "conflict"
+	if _, err := s.Save(loaded); !errors.Is(err, ErrStaleObject) {
+		t.Fatal("expect ErrStaleObject, but got", err)
+	}
+}
+
+func TestSession_UpdateChecksVersion(t *testing.T) {
+	s := NewTestSession().Model(&Document{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	_, _ = s.Insert(&Document{ID: 1, Title: "draft"})
+
+	doc := &Document{ID: 1, Version: 1}
+	if affected, err := s.Model(doc).Update("Title", "final"); err != nil || affected != 1 || doc.Version != 2 {
+		t.Fatal("failed to update with version check", affected, err, doc.Version)
+	}
+	if _, err := s.Model(&Document{ID: 1, Version: 1}).Update("Title", "conflict"); !errors.Is(err, ErrStaleObject) {
+		t.Fatal("expect ErrStaleObject, but got", err)
+	}
+}
@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"database/sql"
+	"go-orm/log"
+	"time"
+)
+
+// RetryPolicy 控制事务遇到死锁等可重试错误时的重试策略
+type RetryPolicy struct {
+	// MaxAttempts 是包括第一次在内的最大执行次数，小于等于 1 时不重试
+	MaxAttempts int
+	// Backoff 是第一次重试前的等待时间，之后每次翻倍
+	Backoff time.Duration
+	// MaxBackoff 是单次等待时间的上限，为 0 时不限制
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy 是 TxOptions 没有指定 Retry 时使用的重试策略
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	Backoff:     50 * time.Millisecond,
+	MaxBackoff:  time.Second,
+}
+
+// sleep 是重试前等待使用的函数，测试时替换以避免真实等待
+var sleep = time.Sleep
+
+// backoff 返回第 attempt 次执行失败后需要等待的时间
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// TxOptions 是 TransactionWithOptions 的配置
+type TxOptions struct {
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+	// Retry 为零值时使用 DefaultRetryPolicy
+	Retry RetryPolicy
+}
+
+// TxResult 是 TransactionWithOptions 的执行结果
+type TxResult struct {
+	// Value 是最后一次执行回调函数的返回值
+	Value interface{}
+	// Attempts 是回调函数被执行的次数
+	Attempts int
+}
+
+// TransactionWithOptions 使用指定的隔离级别和只读选项执行事务
+// 当 dialect 判断错误可以重试时（例如 MySQL 的死锁和锁等待超时），按照重试策略等待后重新执行整个事务
+// 回调函数可能被执行多次，不应该包含事务之外的副作用
+// 与 Transaction 一样总是开启独立的事务，在 TxFunc 中需要嵌套事务时使用 s.Transaction()
+func (engine *Engine) TransactionWithOptions(opts TxOptions, f TxFunc) (result TxResult, err error) {
+	policy := opts.Retry
+	if policy == (RetryPolicy{}) {
+		policy = DefaultRetryPolicy
+	}
+	txOpts := &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly}
+
+	for {
+		result.Attempts++
+		result.Value, err = engine.NewSession().TransactionTx(txOpts, f)
+		if err == nil || result.Attempts >= policy.MaxAttempts || !engine.dialect.IsRetryableError(err) {
+			return
+		}
+		wait := policy.backoff(result.Attempts)
+		log.Infof("transaction attempt %d failed, retry after %v: %v", result.Attempts, wait, err)
+		sleep(wait)
+	}
+}
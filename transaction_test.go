@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"database/sql"
+	"errors"
+	"go-orm/dialect"
+	"go-orm/session"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, Backoff: 10 * time.Millisecond, MaxBackoff: 30 * time.Millisecond}
+	expects := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond, 30 * time.Millisecond}
+	for i, expect := range expects {
+		if d := policy.backoff(i + 1); d != expect {
+			t.Fatalf("attempt %d: expect %v, but got %v", i+1, expect, d)
+		}
+	}
+}
+
+func TestEngine_TransactionWithOptions(t *testing.T) {
+	engine := OpenDB(t)
+	defer engine.Close()
+
+	opts := TxOptions{Isolation: sql.LevelSerializable}
+	result, err := engine.TransactionWithOptions(opts, func(s *session.Session) (interface{}, error) {
+		return s.Model(&User{}).Count()
+	})
+	if err != nil || result.Attempts != 1 {
+		t.Fatal("failed to run transaction with options", err)
+	}
+
+	// 不可重试的错误只执行一次
+	result, err = engine.TransactionWithOptions(opts, func(s *session.Session) (interface{}, error) {
+		return nil, errors.New("Error")
+	})
+	if err == nil || result.Attempts != 1 {
+		t.Fatal("expect no retry, but got attempts", result.Attempts)
+	}
+}
+
+// retryDialect 把所有错误都视为可以重试
+type retryDialect struct {
+	dialect.Dialect
+}
+
+func (retryDialect) IsRetryableError(error) bool {
+	return true
+}
+
+func TestEngine_TransactionRetry(t *testing.T) {
+	engine := OpenDB(t)
+	defer engine.Close()
+	engine.dialect = retryDialect{engine.dialect}
+
+	var waits []time.Duration
+	sleep = func(d time.Duration) { waits = append(waits, d) }
+	defer func() { sleep = time.Sleep }()
+
+	deadlock := errors.New("deadlock")
+	calls := 0
+	opts := TxOptions{Retry: RetryPolicy{MaxAttempts: 3, Backoff: 10 * time.Millisecond}}
+	result, err := engine.TransactionWithOptions(opts, func(s *session.Session) (interface{}, error) {
+		calls++
+		return calls, deadlock
+	})
+	if !errors.Is(err, deadlock) || result.Attempts != 3 || calls != 3 || result.Value != 3 {
+		t.Fatal("expect 3 attempts and the last error, but got", result, err)
+	}
+	if len(waits) != 2 || waits[0] != 10*time.Millisecond || waits[1] != 20*time.Millisecond {
+		t.Fatal("unexpected backoff", waits)
+	}
+}